@@ -0,0 +1,433 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Collection functions take the collection they operate on as their last argument so they can be pipelined,
+// e.g. `{{ .Var "hosts" | without "localhost" | sortAlpha | join "," }}`. They never modify their input;
+// functions that change a collection return a copy.
+
+// dict builds a map from alternating keys and values.
+func dict(pairs ...interface{}) (Vars, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict requires an even number of arguments")
+	}
+	dict := make(Vars, len(pairs)/2)
+	for index := 0; index < len(pairs); index += 2 {
+		key, isString := pairs[index].(string)
+		if !isString {
+			return nil, fmt.Errorf("dict keys must be strings")
+		}
+		dict[key] = pairs[index+1]
+	}
+	return dict, nil
+}
+
+// list builds a list from its arguments.
+func list(values ...interface{}) []interface{} {
+	return append([]interface{}{}, values...)
+}
+
+// set returns a copy of a map with a key set.
+func set(key string, value interface{}, collection interface{}) (Vars, error) {
+	dict, err := toVars(collection)
+	if err != nil {
+		return nil, err
+	}
+	dict[key] = value
+	return dict, nil
+}
+
+// unset returns a copy of a map without a key.
+func unset(key string, collection interface{}) (Vars, error) {
+	dict, err := toVars(collection)
+	if err != nil {
+		return nil, err
+	}
+	delete(dict, key)
+	return dict, nil
+}
+
+// merge deep merges maps, with later maps taking precedence, the same way vars files are merged.
+func merge(collections ...interface{}) (Vars, error) {
+	merged := Vars{}
+	for _, collection := range collections {
+		dict, err := toVars(collection)
+		if err != nil {
+			return nil, err
+		}
+		merged = MergeVars(merged, dict, ListMergeReplace)
+	}
+	return merged, nil
+}
+
+// keys returns the keys of a map, sorted.
+func keys(collection interface{}) ([]string, error) {
+	dict, err := toVars(collection)
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(dict), nil
+}
+
+// values returns the values of a map, ordered by their keys.
+func values(collection interface{}) ([]interface{}, error) {
+	dict, err := toVars(collection)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0, len(dict))
+	for _, key := range sortedKeys(dict) {
+		values = append(values, dict[key])
+	}
+	return values, nil
+}
+
+// pluck returns the value at a path from each map in a list that has one.
+func pluck(path string, collection interface{}) ([]interface{}, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	plucked := []interface{}{}
+	for _, elem := range elems {
+		if reflect.ValueOf(elem).Kind() != reflect.Map {
+			return nil, fmt.Errorf("input must be a slice of maps")
+		}
+		if value, hasValue := lookupVarPath(elem, path); hasValue {
+			plucked = append(plucked, value)
+		}
+	}
+	return plucked, nil
+}
+
+// appendValue returns a copy of a list with a value added to the end.
+func appendValue(value interface{}, collection interface{}) ([]interface{}, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	return append(elems, value), nil
+}
+
+// prependValue returns a copy of a list with a value added to the start.
+func prependValue(value interface{}, collection interface{}) ([]interface{}, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	return append([]interface{}{value}, elems...), nil
+}
+
+// uniq returns a copy of a list with duplicate values removed, keeping the first of each.
+func uniq(collection interface{}) ([]interface{}, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	unique := []interface{}{}
+	for _, elem := range elems {
+		if !containsValue(unique, elem) {
+			unique = append(unique, elem)
+		}
+	}
+	return unique, nil
+}
+
+// without returns a copy of a list with every given value removed; the list is the last argument.
+func without(args ...interface{}) ([]interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("input must be a slice")
+	}
+	elems, err := toSlice(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	removed := args[:len(args)-1]
+	remaining := []interface{}{}
+	for _, elem := range elems {
+		if !containsValue(removed, elem) {
+			remaining = append(remaining, elem)
+		}
+	}
+	return remaining, nil
+}
+
+// has returns if a list contains a value, or a map has a key.
+func has(value interface{}, collection interface{}) (bool, error) {
+	if reflect.ValueOf(collection).Kind() == reflect.Map {
+		dict, err := toVars(collection)
+		if err != nil {
+			return false, err
+		}
+		_, hasKey := dict[fmt.Sprintf("%v", value)]
+		return hasKey, nil
+	}
+	elems, err := toSlice(collection)
+	if err != nil {
+		return false, fmt.Errorf("input must be a slice or a map")
+	}
+	return containsValue(elems, value), nil
+}
+
+// sortAlpha returns the values of a list as strings, sorted.
+func sortAlpha(collection interface{}) ([]string, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]string, len(elems))
+	for index, elem := range elems {
+		sorted[index] = fmt.Sprintf("%v", elem)
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+// sortBy returns a copy of a list of maps sorted by the value at a path in each.
+// Numbers sort numerically, everything else by its string form; maps without the path sort last.
+func sortBy(path string, collection interface{}) ([]interface{}, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range elems {
+		if reflect.ValueOf(elem).Kind() != reflect.Map {
+			return nil, fmt.Errorf("input must be a slice of maps")
+		}
+	}
+	sort.SliceStable(elems, func(i, j int) bool {
+		a, aHasValue := lookupVarPath(elems[i], path)
+		b, bHasValue := lookupVarPath(elems[j], path)
+		if !aHasValue || !bHasValue {
+			return aHasValue && !bHasValue
+		}
+		return lessValue(a, b)
+	})
+	return elems, nil
+}
+
+// reverse returns a copy of a list in reverse order.
+func reverse(collection interface{}) ([]interface{}, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	reversed := make([]interface{}, len(elems))
+	for index, elem := range elems {
+		reversed[len(elems)-1-index] = elem
+	}
+	return reversed, nil
+}
+
+// compact returns a copy of a list without empty values, as `empty` defines them.
+func compact(collection interface{}) ([]interface{}, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	compacted := []interface{}{}
+	for _, elem := range elems {
+		if !isEmptyValue(elem) {
+			compacted = append(compacted, elem)
+		}
+	}
+	return compacted, nil
+}
+
+// groupBy groups a list of maps into lists keyed by the value at a path in each. Maps without the path are dropped.
+func groupBy(path string, collection interface{}) (Vars, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	groups := Vars{}
+	for _, elem := range elems {
+		if reflect.ValueOf(elem).Kind() != reflect.Map {
+			return nil, fmt.Errorf("input must be a slice of maps")
+		}
+		value, hasValue := lookupVarPath(elem, path)
+		if !hasValue {
+			continue
+		}
+		key := fmt.Sprintf("%v", value)
+		group, _ := groups[key].([]interface{})
+		groups[key] = append(group, elem)
+	}
+	return groups, nil
+}
+
+// first returns the first element of a collection, or nil if it's empty.
+// From a channel it receives, and so consumes, one value.
+func first(collection interface{}) (interface{}, error) {
+	elems, err := toSequence(collection, 1)
+	if err != nil {
+		return nil, err
+	}
+	if elems.Len() == 0 {
+		return nil, nil
+	}
+	return elems.Index(0).Interface(), nil
+}
+
+// last returns the last element of a collection, or nil if it's empty.
+// From a channel it receives, and so consumes, every value; the channel must be closed.
+func last(collection interface{}) (interface{}, error) {
+	elems, err := toSequence(collection, -1)
+	if err != nil {
+		return nil, err
+	}
+	if elems.Len() == 0 {
+		return nil, nil
+	}
+	return elems.Index(elems.Len() - 1).Interface(), nil
+}
+
+// at returns the element of a collection at an index.
+// From a channel it receives, and so consumes, the values up to and including the index.
+func at(index int, collection interface{}) (interface{}, error) {
+	elems, err := toSequence(collection, maxInt(index+1, 0))
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= elems.Len() {
+		return nil, fmt.Errorf("index %d out of range for length %d", index, elems.Len())
+	}
+	return elems.Index(index).Interface(), nil
+}
+
+// sliceCollection returns the elements of a collection from one index up to, but not including, another.
+// Slicing a string returns a string. From a channel it receives, and so consumes, the values before `to`.
+func sliceCollection(from, to int, collection interface{}) (interface{}, error) {
+	elems, err := toSequence(collection, maxInt(to, 0))
+	if err != nil {
+		return nil, err
+	}
+	if from < 0 || to < from || to > elems.Len() {
+		return nil, fmt.Errorf("slice bounds [%d:%d] out of range for length %d", from, to, elems.Len())
+	}
+	sliced := elems.Slice(from, to)
+	if reflect.ValueOf(collection).Kind() == reflect.String {
+		return strings.Join(sliced.Interface().([]string), ""), nil
+	}
+	return sliced.Interface(), nil
+}
+
+// join joins the elements of a collection, as strings, with a separator.
+// From a channel it receives, and so consumes, every value; the channel must be closed.
+func join(sep string, collection interface{}) (string, error) {
+	elems, err := toSequence(collection, -1)
+	if err != nil {
+		return "", err
+	}
+	values := make([]string, elems.Len())
+	for index := range values {
+		values[index] = fmt.Sprintf("%v", elems.Index(index).Interface())
+	}
+	return strings.Join(values, sep), nil
+}
+
+// toSequence returns the elements of a collection as a slice value: slices as they are, arrays copied,
+// maps as their values ordered by their keys, strings as their characters and channels as the values received.
+// Only the first `limit` values are received from a channel, so a channel that is still open isn't drained;
+// a negative limit receives every value until the channel is closed. Received values are consumed, and a nil
+// channel is an error, as receiving from it would block forever.
+func toSequence(collection interface{}, limit int) (reflect.Value, error) {
+	value := reflect.ValueOf(collection)
+	switch value.Kind() {
+	case reflect.Slice:
+		return value, nil
+	case reflect.Array:
+		elems := reflect.MakeSlice(reflect.SliceOf(value.Type().Elem()), value.Len(), value.Len())
+		reflect.Copy(elems, value)
+		return elems, nil
+	case reflect.Map:
+		elems, err := values(collection)
+		return reflect.ValueOf(elems), err
+	case reflect.String:
+		return reflect.ValueOf(strings.Split(value.String(), "")), nil
+	case reflect.Chan:
+		if value.Type().ChanDir()&reflect.RecvDir == 0 {
+			return reflect.Value{}, fmt.Errorf("input must be a receivable channel")
+		}
+		if value.IsNil() {
+			return reflect.Value{}, fmt.Errorf("input must not be a nil channel")
+		}
+		elems := reflect.MakeSlice(reflect.SliceOf(value.Type().Elem()), 0, 0)
+		for limit < 0 || elems.Len() < limit {
+			elem, received := value.Recv()
+			if !received {
+				break
+			}
+			elems = reflect.Append(elems, elem)
+		}
+		return elems, nil
+	}
+	return reflect.Value{}, fmt.Errorf("input must be a slice, array, map, string or channel")
+}
+
+// toSlice copies any slice or array into a []interface{}.
+func toSlice(collection interface{}) ([]interface{}, error) {
+	value := reflect.ValueOf(collection)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, fmt.Errorf("input must be a slice")
+	}
+	elems := make([]interface{}, value.Len())
+	for index := range elems {
+		elems[index] = value.Index(index).Interface()
+	}
+	return elems, nil
+}
+
+// toVars copies any map into Vars, converting its keys to strings.
+func toVars(collection interface{}) (Vars, error) {
+	value := reflect.ValueOf(collection)
+	if value.Kind() != reflect.Map {
+		return nil, fmt.Errorf("input must be a map")
+	}
+	dict := make(Vars, value.Len())
+	for _, key := range value.MapKeys() {
+		dict[fmt.Sprintf("%v", key.Interface())] = value.MapIndex(key).Interface()
+	}
+	return dict, nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func sortedKeys(dict Vars) []string {
+	keys := make([]string, 0, len(dict))
+	for key := range dict {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsValue(elems []interface{}, value interface{}) bool {
+	for _, elem := range elems {
+		if reflect.DeepEqual(elem, value) || schemaValuesEqual(elem, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// lessValue orders two values numerically if both are numbers, otherwise by their string forms.
+func lessValue(a, b interface{}) bool {
+	aNumber, aErr := strconv.ParseFloat(fmt.Sprintf("%v", a), 64)
+	bNumber, bErr := strconv.ParseFloat(fmt.Sprintf("%v", b), 64)
+	if aErr == nil && bErr == nil {
+		return aNumber < bNumber
+	}
+	return fmt.Sprintf("%v", a) < fmt.Sprintf("%v", b)
+}
@@ -0,0 +1,202 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func renderCollections(body string, vars Vars) (string, error) {
+	buffer := bytes.NewBuffer(nil)
+	err := New().WithBody(body).WithVars(vars).Process(buffer)
+	return buffer.String(), err
+}
+
+func TestCollectionsDictAndList(t *testing.T) {
+	assert := assert.New(t)
+
+	output, err := renderCollections(`{{ $d := dict "b" 2 "a" 1 }}{{ range $k, $v := $d }}{{ $k }}={{ $v }};{{ end }}{{ list 1 "two" 3 }}`, nil)
+	assert.Nil(err)
+	assert.Equal("a=1;b=2;[1 two 3]", output)
+
+	_, err = renderCollections(`{{ dict "a" }}`, nil)
+	assert.NotNil(err)
+	_, err = renderCollections(`{{ dict 1 2 }}`, nil)
+	assert.NotNil(err)
+}
+
+func TestCollectionsMaps(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := Vars{
+		"labels": map[interface{}]interface{}{"app": "web", "tier": "frontend"},
+		"extra":  map[string]interface{}{"tier": "backend", "team": "platform"},
+	}
+
+	output, err := renderCollections(`{{ .Var "labels" | keys }} {{ .Var "labels" | values }}`, vars)
+	assert.Nil(err)
+	assert.Equal("[app tier] [web frontend]", output)
+
+	output, err = renderCollections(`{{ .Var "labels" | set "env" "prod" | unset "app" | keys }} {{ .Var "labels" | keys }}`, vars)
+	assert.Nil(err)
+	assert.Equal("[env tier] [app tier]", output)
+
+	output, err = renderCollections(`{{ $m := merge (.Var "labels") (.Var "extra") }}{{ $m.tier }} {{ $m.app }} {{ $m.team }}`, vars)
+	assert.Nil(err)
+	assert.Equal("backend web platform", output)
+
+	output, err = renderCollections(`{{ .Var "labels" | has "app" }} {{ .Var "labels" | has "env" }}`, vars)
+	assert.Nil(err)
+	assert.Equal("true false", output)
+
+	_, err = renderCollections(`{{ "foo" | keys }}`, nil)
+	assert.NotNil(err)
+	assert.Contains("input must be a map", err.Error())
+}
+
+func TestCollectionsLists(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := Vars{
+		"hosts": []interface{}{"b", "a", "", "b", "c"},
+		"ports": []int{80, 443},
+	}
+
+	output, err := renderCollections(`{{ .Var "hosts" | uniq }} {{ .Var "hosts" | compact | sortAlpha }} {{ .Var "hosts" | without "b" "" }}`, vars)
+	assert.Nil(err)
+	assert.Equal("[b a  c] [a b b c] [a c]", output)
+
+	output, err = renderCollections(`{{ .Var "ports" | append 8080 | prepend 22 }} {{ .Var "ports" | reverse }} {{ .Var "ports" | has 443 }}`, vars)
+	assert.Nil(err)
+	assert.Equal("[22 80 443 8080] [443 80] true", output)
+
+	_, err = renderCollections(`{{ "foo" | uniq }}`, nil)
+	assert.NotNil(err)
+	assert.Contains("input must be a slice", err.Error())
+}
+
+func TestCollectionsListsOfMaps(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := Vars{
+		"services": []interface{}{
+			map[interface{}]interface{}{"name": "web", "tier": "frontend", "port": 8080},
+			map[interface{}]interface{}{"name": "api", "tier": "backend", "port": 443},
+			map[interface{}]interface{}{"name": "db", "tier": "backend", "port": 5432},
+			map[interface{}]interface{}{"name": "cron"},
+		},
+	}
+
+	output, err := renderCollections(`{{ .Var "services" | pluck "port" }} {{ .Var "services" | sortBy "port" | pluck "name" }} {{ .Var "services" | sortBy "name" | pluck "name" }}`, vars)
+	assert.Nil(err)
+	assert.Equal("[8080 443 5432] [api db web cron] [api cron db web]", output)
+
+	output, err = renderCollections(`{{ range $tier, $services := .Var "services" | groupBy "tier" }}{{ $tier }}={{ $services | pluck "name" }};{{ end }}`, vars)
+	assert.Nil(err)
+	assert.Equal("backend=[api db];frontend=[web];", output)
+
+	_, err = renderCollections(`{{ list 1 2 | pluck "name" }}`, nil)
+	assert.NotNil(err)
+	assert.Contains("input must be a slice of maps", err.Error())
+}
+
+func TestCollectionsIndexing(t *testing.T) {
+	assert := assert.New(t)
+
+	ports := make(chan int, 3)
+	ports <- 80
+	ports <- 443
+	ports <- 8080
+	close(ports)
+
+	vars := Vars{
+		"labels": map[interface{}]interface{}{"tier": "frontend", "app": "web", "env": "prod"},
+		"zones":  [3]string{"a", "b", "c"},
+		"name":   "héllo",
+		"ports":  ports,
+	}
+
+	output, err := renderCollections(`{{ .Var "labels" | first }} {{ .Var "labels" | last }} {{ .Var "labels" | at 1 }} {{ .Var "labels" | slice 0 2 }} {{ .Var "labels" | join "," }}`, vars)
+	assert.Nil(err)
+	assert.Equal("web frontend prod [web prod] web,prod,frontend", output)
+
+	output, err = renderCollections(`{{ .Var "zones" | first }} {{ .Var "zones" | last }} {{ .Var "zones" | slice 1 3 }} {{ .Var "zones" | join "/" }}`, vars)
+	assert.Nil(err)
+	assert.Equal("a c [b c] a/b/c", output)
+
+	output, err = renderCollections(`{{ .Var "name" | first }} {{ .Var "name" | at 1 }} {{ .Var "name" | slice 1 4 }} {{ .Var "name" | join "-" }}`, vars)
+	assert.Nil(err)
+	assert.Equal("h é éll h-é-l-l-o", output)
+
+	output, err = renderCollections(`{{ .Var "ports" | join "," }}`, vars)
+	assert.Nil(err)
+	assert.Equal("80,443,8080", output)
+
+	output, err = renderCollections(`{{ list | first }} {{ list | join "," }}`, nil)
+	assert.Nil(err)
+	assert.Equal("<no value> ", output)
+}
+
+func TestCollectionsIndexingOpenChannel(t *testing.T) {
+	assert := assert.New(t)
+
+	ports := make(chan int, 4)
+	ports <- 80
+	ports <- 443
+	ports <- 8080
+	ports <- 8443
+
+	output, err := renderCollections(`{{ .Var "ports" | first }} {{ .Var "ports" | at 1 }} {{ .Var "ports" | slice 0 1 }} {{ .Var "ports" | at -2 }}`, Vars{"ports": ports})
+	assert.NotNil(err)
+	assert.Contains("index -2 out of range for length 0", err.Error())
+	assert.Equal("80 8080 [8443] ", output)
+	assert.Len(ports, 0)
+
+	ports <- 80
+	ports <- 443
+	output, err = renderCollections(`{{ .Var "ports" | first }}`, Vars{"ports": ports})
+	assert.Nil(err)
+	assert.Equal("80", output)
+	assert.Len(ports, 1)
+}
+
+func TestCollectionsIndexingNilChannel(t *testing.T) {
+	assert := assert.New(t)
+
+	var ports chan int
+	for _, body := range []string{
+		`{{ .Var "ports" | first }}`,
+		`{{ .Var "ports" | at 0 }}`,
+		`{{ .Var "ports" | slice 0 1 }}`,
+		`{{ .Var "ports" | last }}`,
+		`{{ .Var "ports" | join "," }}`,
+	} {
+		_, err := renderCollections(body, Vars{"ports": ports})
+		assert.NotNil(err, body)
+		assert.Contains("input must not be a nil channel", err.Error())
+	}
+}
+
+func TestCollectionsIndexingOutOfRange(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := renderCollections(`{{ list 1 2 | at 2 }}`, nil)
+	assert.NotNil(err)
+	assert.Contains("index 2 out of range for length 2", err.Error())
+
+	_, err = renderCollections(`{{ list 1 2 | at -1 }}`, nil)
+	assert.NotNil(err)
+	assert.Contains("index -1 out of range for length 2", err.Error())
+
+	_, err = renderCollections(`{{ "foo" | slice 1 4 }}`, nil)
+	assert.NotNil(err)
+	assert.Contains("slice bounds [1:4] out of range for length 3", err.Error())
+
+	_, err = renderCollections(`{{ list 1 2 | slice 2 1 }}`, nil)
+	assert.NotNil(err)
+
+	_, err = renderCollections(`{{ 1 | first }}`, nil)
+	assert.NotNil(err)
+	assert.Contains("input must be a slice, array, map, string or channel", err.Error())
+}
@@ -0,0 +1,238 @@
+package template
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ParseKey decodes a base64 encoded AES key, such as one made by `Helpers.CreateKey`.
+// The key must be 16, 24 or 32 bytes long.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %v", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("invalid key: must be 16, 24 or 32 bytes, got %d", len(key))
+	}
+}
+
+// Encrypt encrypts plaintext with AES-GCM, returning the nonce and ciphertext base64 encoded.
+func Encrypt(key, plaintext []byte) (string, error) {
+	return seal(key, plaintext, nil)
+}
+
+// Decrypt decrypts the output of `Encrypt`.
+func Decrypt(key []byte, ciphertext string) ([]byte, error) {
+	return open(key, ciphertext, nil)
+}
+
+// seal encrypts plaintext with AES-GCM, authenticating additionalData along with it.
+func seal(key, plaintext, additionalData []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, additionalData)), nil
+}
+
+// open decrypts the output of `seal`; additionalData must match what it was sealed with.
+func open(key []byte, ciphertext string, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ciphertext))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %v", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid ciphertext: too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt: wrong key or corrupted ciphertext")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptedPrefix and encryptedSuffix wrap encrypted values and files, e.g. `ENC[<base64>]`.
+const (
+	encryptedPrefix = "ENC["
+	encryptedSuffix = "]"
+)
+
+// IsEncrypted returns if a value, or the contents of a whole file, is wrapped as `ENC[<base64>]`.
+func IsEncrypted(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// EncryptFile encrypts the contents of a whole file, returning them wrapped as `ENC[<base64>]`.
+func EncryptFile(key, contents []byte) ([]byte, error) {
+	ciphertext, err := Encrypt(key, contents)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(encryptedPrefix + ciphertext + encryptedSuffix + "\n"), nil
+}
+
+// DecryptFile decrypts the output of `EncryptFile`.
+func DecryptFile(key, contents []byte) ([]byte, error) {
+	if !IsEncrypted(string(contents)) {
+		return nil, fmt.Errorf("contents are not encrypted")
+	}
+	return Decrypt(key, unwrapEncrypted(string(contents)))
+}
+
+// EncryptValue encrypts a value, returning it wrapped as `ENC[<base64>]`.
+// The value is encoded as json first, so its type survives decryption. The path is the value's dotted key path,
+// such as `db.password`; it is authenticated with the value, so the value can't be moved to another key.
+func EncryptValue(key []byte, path string, value interface{}) (string, error) {
+	encoded, err := json.Marshal(normalizeValue(value))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(key, encoded, []byte(path))
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + ciphertext + encryptedSuffix, nil
+}
+
+// DecryptValue decrypts the output of `EncryptValue`; the path must be the one the value was encrypted at.
+func DecryptValue(key []byte, path, value string) (interface{}, error) {
+	if !IsEncrypted(value) {
+		return nil, fmt.Errorf("value is not encrypted")
+	}
+	plaintext, err := open(key, unwrapEncrypted(value), []byte(path))
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := yaml.Unmarshal(plaintext, &decoded); err != nil {
+		return nil, err
+	}
+	return normalizeValue(decoded), nil
+}
+
+// EncryptVars returns a copy of vars with every leaf value encrypted with `EncryptValue` at its key path, leaving
+// the keys readable.
+func EncryptVars(key []byte, vars Vars) (Vars, error) {
+	encrypted, err := mapLeafValues(normalizeValue(vars), "", func(path string, value interface{}) (interface{}, error) {
+		if str, isString := value.(string); isString && IsEncrypted(str) {
+			return value, nil
+		}
+		return EncryptValue(key, path, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return encrypted.(map[string]interface{}), nil
+}
+
+// DecryptVars returns a copy of vars with every value encrypted with `EncryptValue` decrypted.
+func DecryptVars(key []byte, vars Vars) (Vars, error) {
+	return decryptVars(key, vars, false)
+}
+
+// DecryptSensitiveVars is like `DecryptVars`, but wraps every decrypted value in `Sensitive`.
+func DecryptSensitiveVars(key []byte, vars Vars) (Vars, error) {
+	return decryptVars(key, vars, true)
+}
+
+func decryptVars(key []byte, vars Vars, sensitive bool) (Vars, error) {
+	decrypted, err := mapLeafValues(normalizeValue(vars), "", func(path string, value interface{}) (interface{}, error) {
+		str, isString := value.(string)
+		if !isString || !IsEncrypted(str) {
+			return value, nil
+		}
+		decryptedValue, err := DecryptValue(key, path, str)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", schemaPathName(path), err)
+		}
+		if sensitive {
+			return markSensitive(decryptedValue, nil, nil, true), nil
+		}
+		return decryptedValue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decrypted.(map[string]interface{}), nil
+}
+
+// HasEncryptedValues returns if any value in vars is encrypted.
+func HasEncryptedValues(vars Vars) bool {
+	var found bool
+	mapLeafValues(normalizeValue(vars), "", func(path string, value interface{}) (interface{}, error) {
+		if str, isString := value.(string); isString && IsEncrypted(str) {
+			found = true
+		}
+		return value, nil
+	})
+	return found
+}
+
+// mapLeafValues returns a copy of a normalized value with every value other than maps and lists replaced by mapper.
+func mapLeafValues(value interface{}, path string, mapper func(path string, value interface{}) (interface{}, error)) (interface{}, error) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		// visit keys in order so the first error is always the same one.
+		sort.Strings(keys)
+		mapped := make(map[string]interface{}, len(typed))
+		for _, key := range keys {
+			mappedElem, err := mapLeafValues(typed[key], joinVarPath(path, key), mapper)
+			if err != nil {
+				return nil, err
+			}
+			mapped[key] = mappedElem
+		}
+		return mapped, nil
+	case []interface{}:
+		mapped := make([]interface{}, len(typed))
+		for index, elem := range typed {
+			mappedElem, err := mapLeafValues(elem, fmt.Sprintf("%s[%d]", path, index), mapper)
+			if err != nil {
+				return nil, err
+			}
+			mapped[index] = mappedElem
+		}
+		return mapped, nil
+	default:
+		return mapper(path, value)
+	}
+}
+
+func unwrapEncrypted(value string) string {
+	value = strings.TrimSpace(value)
+	return strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), encryptedSuffix)
+}
@@ -0,0 +1,118 @@
+package template
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := ParseKey(Helpers{}.CreateKey(32))
+	assert.Nil(err)
+
+	ciphertext, err := Encrypt(key, []byte("hunter2"))
+	assert.Nil(err)
+	assert.NotContains("hunter2", ciphertext)
+
+	plaintext, err := Decrypt(key, ciphertext)
+	assert.Nil(err)
+	assert.Equal("hunter2", string(plaintext))
+
+	otherKey, err := ParseKey(Helpers{}.CreateKey(16))
+	assert.Nil(err)
+	_, err = Decrypt(otherKey, ciphertext)
+	assert.NotNil(err)
+
+	_, err = Decrypt(key, "not base64!")
+	assert.NotNil(err)
+}
+
+func TestParseKey(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := ParseKey(Helpers{}.CreateKey(24))
+	assert.Nil(err)
+
+	_, err = ParseKey(Helpers{}.CreateKey(20))
+	assert.NotNil(err)
+
+	_, err = ParseKey("not base64!")
+	assert.NotNil(err)
+}
+
+func TestEncryptDecryptFile(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := ParseKey(Helpers{}.CreateKey(32))
+	assert.Nil(err)
+
+	encrypted, err := EncryptFile(key, []byte("foo: bar\n"))
+	assert.Nil(err)
+	assert.True(IsEncrypted(string(encrypted)))
+
+	decrypted, err := DecryptFile(key, encrypted)
+	assert.Nil(err)
+	assert.Equal("foo: bar\n", string(decrypted))
+
+	_, err = DecryptFile(key, []byte("foo: bar\n"))
+	assert.NotNil(err)
+}
+
+func TestEncryptDecryptVars(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := ParseKey(Helpers{}.CreateKey(32))
+	assert.Nil(err)
+
+	vars := Vars{
+		"name": "test-service",
+		"db": map[interface{}]interface{}{
+			"password": "hunter2",
+			"port":     5432,
+		},
+		"hosts": []interface{}{"a", "b"},
+	}
+	encrypted, err := EncryptVars(key, vars)
+	assert.Nil(err)
+	assert.True(HasEncryptedValues(encrypted))
+	assert.True(IsEncrypted(encrypted["name"].(string)))
+	assert.True(IsEncrypted(encrypted["db"].(map[string]interface{})["password"].(string)))
+	assert.True(IsEncrypted(encrypted["hosts"].([]interface{})[1].(string)))
+	assert.False(HasEncryptedValues(vars))
+
+	decrypted, err := DecryptVars(key, encrypted)
+	assert.Nil(err)
+	assert.Equal("test-service", decrypted["name"])
+	assert.Equal("hunter2", decrypted["db"].(map[string]interface{})["password"])
+	assert.Equal(5432, decrypted["db"].(map[string]interface{})["port"])
+	assert.Equal("b", decrypted["hosts"].([]interface{})[1])
+
+	otherKey, err := ParseKey(Helpers{}.CreateKey(32))
+	assert.Nil(err)
+	_, err = DecryptVars(otherKey, encrypted)
+	assert.NotNil(err)
+	assert.Contains("db.password", err.Error())
+}
+
+func TestEncryptVarsBindsKeyPath(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := ParseKey(Helpers{}.CreateKey(32))
+	assert.Nil(err)
+
+	encrypted, err := EncryptVars(key, Vars{"admin_password": "hunter2", "guest_password": "guest"})
+	assert.Nil(err)
+
+	swapped := Vars{"admin_password": encrypted["guest_password"], "guest_password": encrypted["admin_password"]}
+	_, err = DecryptVars(key, swapped)
+	assert.NotNil(err)
+	assert.Contains("admin_password", err.Error())
+
+	value, err := DecryptValue(key, "guest_password", encrypted["guest_password"].(string))
+	assert.Nil(err)
+	assert.Equal("guest", value)
+	_, err = DecryptValue(key, "db.guest_password", encrypted["guest_password"].(string))
+	assert.NotNil(err)
+}
@@ -0,0 +1,183 @@
+package template
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// ReferenceKind is the kind of value a template references.
+type ReferenceKind string
+
+const (
+	// ReferenceVar is a reference to a template variable, i.e. `.Var` or `.HasVar`.
+	ReferenceVar ReferenceKind = "var"
+	// ReferenceEnv is a reference to an environment variable, i.e. `.Env` or `.HasEnv`.
+	ReferenceEnv ReferenceKind = "env"
+	// ReferenceFile is a reference to a file, i.e. `.File` or `.HasFile`.
+	ReferenceFile ReferenceKind = "file"
+)
+
+// referenceMethods maps the template methods that reference values to the kind of value they reference.
+var referenceMethods = map[string]ReferenceKind{
+	"Var":     ReferenceVar,
+	"HasVar":  ReferenceVar,
+	"Env":     ReferenceEnv,
+	"HasEnv":  ReferenceEnv,
+	"File":    ReferenceFile,
+	"HasFile": ReferenceFile,
+}
+
+// Reference is a single use of a variable, environment variable or file within a template.
+type Reference struct {
+	Kind ReferenceKind `json:"kind"`
+	// Method is the template method making the reference, e.g. `Var` or `HasVar`.
+	Method string `json:"method"`
+	// Key is the variable name, environment variable name or file path.
+	Key string `json:"key"`
+	// HasDefault is true if a default value was supplied to the method.
+	HasDefault bool `json:"hasDefault"`
+	// Default is the default value as written in the template, e.g. `"sandbox"` or `$defaultReplicas`.
+	Default  string `json:"default,omitempty"`
+	Template string `json:"template"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+// IsRequired returns if the reference fails to render when its key is unset, i.e. it is a `.Var` or `.Env`
+// without a default, or a `.File`.
+func (r Reference) IsRequired() bool {
+	return !r.HasDefault && !strings.HasPrefix(r.Method, "Has")
+}
+
+// Location returns the `template:line:column` location of the reference.
+func (r Reference) Location() string {
+	if r.Line == 0 {
+		return r.Template
+	}
+	return r.Template + ":" + strconv.Itoa(r.Line) + ":" + strconv.Itoa(r.Column)
+}
+
+// findReferences walks the parse trees of the given templates and returns every reference
+// made with a literal key, ordered by template and position.
+func findReferences(templates []*texttemplate.Template) []Reference {
+	var references []Reference
+	seen := map[*parse.Tree]bool{}
+	for _, temp := range templates {
+		if temp.Tree == nil || temp.Tree.Root == nil || seen[temp.Tree] {
+			continue
+		}
+		seen[temp.Tree] = true
+		walkReferences(temp.Tree, temp.Tree.Root, &references)
+	}
+
+	sort.SliceStable(references, func(i, j int) bool {
+		if references[i].Template != references[j].Template {
+			return references[i].Template < references[j].Template
+		}
+		if references[i].Line != references[j].Line {
+			return references[i].Line < references[j].Line
+		}
+		return references[i].Column < references[j].Column
+	})
+	return references
+}
+
+func walkReferences(tree *parse.Tree, node parse.Node, references *[]Reference) {
+	switch typed := node.(type) {
+	case *parse.ListNode:
+		if typed == nil {
+			return
+		}
+		for _, child := range typed.Nodes {
+			walkReferences(tree, child, references)
+		}
+	case *parse.ActionNode:
+		walkReferences(tree, typed.Pipe, references)
+	case *parse.IfNode:
+		walkBranchReferences(tree, &typed.BranchNode, references)
+	case *parse.RangeNode:
+		walkBranchReferences(tree, &typed.BranchNode, references)
+	case *parse.WithNode:
+		walkBranchReferences(tree, &typed.BranchNode, references)
+	case *parse.TemplateNode:
+		walkReferences(tree, typed.Pipe, references)
+	case *parse.PipeNode:
+		if typed == nil {
+			return
+		}
+		for _, cmd := range typed.Cmds {
+			walkReferences(tree, cmd, references)
+		}
+	case *parse.CommandNode:
+		if reference, isReference := commandReference(tree, typed); isReference {
+			*references = append(*references, reference)
+		}
+		for _, arg := range typed.Args {
+			walkReferences(tree, arg, references)
+		}
+	case *parse.ChainNode:
+		walkReferences(tree, typed.Node, references)
+	}
+}
+
+func walkBranchReferences(tree *parse.Tree, branch *parse.BranchNode, references *[]Reference) {
+	walkReferences(tree, branch.Pipe, references)
+	walkReferences(tree, branch.List, references)
+	walkReferences(tree, branch.ElseList, references)
+}
+
+// commandReference returns the reference made by a command such as `.Var "foo" "bar"`,
+// if the command calls one of the reference methods with a literal key.
+func commandReference(tree *parse.Tree, cmd *parse.CommandNode) (Reference, bool) {
+	if len(cmd.Args) < 2 {
+		return Reference{}, false
+	}
+
+	var method string
+	switch typed := cmd.Args[0].(type) {
+	case *parse.FieldNode:
+		if len(typed.Ident) == 1 {
+			method = typed.Ident[0]
+		}
+	case *parse.VariableNode:
+		if len(typed.Ident) == 2 && typed.Ident[0] == "$" {
+			method = typed.Ident[1]
+		}
+	}
+	kind, isReference := referenceMethods[method]
+	if !isReference {
+		return Reference{}, false
+	}
+
+	key, isLiteral := cmd.Args[1].(*parse.StringNode)
+	if !isLiteral {
+		return Reference{}, false
+	}
+
+	reference := Reference{
+		Kind:       kind,
+		Method:     method,
+		Key:        key.Text,
+		HasDefault: len(cmd.Args) > 2,
+	}
+	if reference.HasDefault {
+		reference.Default = cmd.Args[2].String()
+	}
+	reference.Template, reference.Line, reference.Column = nodeLocation(tree, cmd)
+	return reference, true
+}
+
+// nodeLocation returns the template name, line and column of a node.
+func nodeLocation(tree *parse.Tree, node parse.Node) (name string, line, column int) {
+	location, _ := tree.ErrorContext(node)
+	pieces := strings.Split(location, ":")
+	if len(pieces) < 3 {
+		return location, 0, 0
+	}
+	line, _ = strconv.Atoi(pieces[len(pieces)-2])
+	column, _ = strconv.Atoi(pieces[len(pieces)-1])
+	return strings.Join(pieces[:len(pieces)-2], ":"), line, column
+}
@@ -0,0 +1,50 @@
+package template
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestTemplateReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{{ $default := "bar" }}
+{{ .Var "foo" $default | upper }}
+{{- if .HasEnv "HOME" }}{{ .Env "HOME" "/root" }}{{ end }}
+{{ range $key, $value := ( .Var "list" ) }}{{ $.File "testdata/inline_file" }}{{ end }}
+{{ template "sub" . }}`
+	sub := `{{ define "sub" }}{{ with .Var "sub" 3 }}{{ . }}{{ end }}{{ end }}`
+
+	references, err := New().WithName("main").WithBody(test).WithInclude(sub).References()
+	assert.Nil(err)
+	assert.Len(references, 6)
+
+	// includes are parsed under the name of the main template.
+	assert.Equal("sub", references[0].Key)
+	assert.Equal("3", references[0].Default)
+	assert.Equal(1, references[0].Line)
+
+	assert.Equal(Reference{Kind: ReferenceVar, Method: "Var", Key: "foo", HasDefault: true, Default: "$default", Template: "main", Line: 2, Column: 3}, references[1])
+	assert.Equal(Reference{Kind: ReferenceEnv, Method: "HasEnv", Key: "HOME", Template: "main", Line: 3, Column: 7}, references[2])
+	assert.Equal(Reference{Kind: ReferenceEnv, Method: "Env", Key: "HOME", HasDefault: true, Default: `"/root"`, Template: "main", Line: 3, Column: 27}, references[3])
+	assert.Equal("list", references[4].Key)
+	assert.True(references[4].IsRequired())
+	assert.Equal(ReferenceFile, references[5].Kind)
+	assert.Equal("testdata/inline_file", references[5].Key)
+}
+
+func TestTemplateReferencesParseError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := New().WithBody(`{{ .Var "foo" `).References()
+	assert.NotNil(err)
+}
+
+func TestReferenceIsRequired(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(Reference{Method: "Var"}.IsRequired())
+	assert.False(Reference{Method: "Var", HasDefault: true}.IsRequired())
+	assert.False(Reference{Method: "HasVar"}.IsRequired())
+}
@@ -0,0 +1,90 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sandbox restricts what a template can read from the host through `.File`, `.HasFile`, `.Env` and `.HasEnv`.
+type Sandbox struct {
+	// FileRoots are the directories files can be read from. Paths that resolve outside every root,
+	// through `..` or through symlinks, are rejected. If empty, no files can be read.
+	FileRoots []string
+	// EnvAllow are the env variable names the template can read; a name ending in `*` allows every
+	// variable with that prefix. Any other env variable appears unset. If empty, no env variables can be read.
+	EnvAllow []string
+}
+
+// SandboxError is returned when a template reaches outside its sandbox.
+type SandboxError struct {
+	Kind ReferenceKind
+	Key  string
+}
+
+// Error implements error.
+func (e *SandboxError) Error() string {
+	if e.Kind == ReferenceEnv {
+		return fmt.Sprintf("template sandbox: env variable `%s` is not allowed", e.Key)
+	}
+	return fmt.Sprintf("template sandbox: file `%s` is outside the allowed roots", e.Key)
+}
+
+// AllowsEnv returns if an env variable can be read.
+func (s *Sandbox) AllowsEnv(key string) bool {
+	for _, allowed := range s.EnvAllow {
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		} else if key == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckFile returns a `*SandboxError` if the path resolves outside the allowed roots.
+func (s *Sandbox) CheckFile(path string) error {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	for _, root := range s.FileRoots {
+		resolvedRoot, err := resolvePath(root)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(resolvedRoot, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return &SandboxError{Kind: ReferenceFile, Key: path}
+}
+
+// resolvePath returns the absolute path with all symlinks evaluated.
+// If the path doesn't exist, the closest existing parent directory is resolved instead.
+func resolvePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(absPath)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(absPath)
+		if parent == absPath {
+			return filepath.Join(append([]string{absPath}, missing...)...), nil
+		}
+		missing = append([]string{filepath.Base(absPath)}, missing...)
+		absPath = parent
+	}
+}
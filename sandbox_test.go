@@ -0,0 +1,104 @@
+package template
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestSandboxAllowsEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	sandbox := &Sandbox{EnvAllow: []string{"HOME", "APP_*"}}
+	assert.True(sandbox.AllowsEnv("HOME"))
+	assert.True(sandbox.AllowsEnv("APP_NAME"))
+	assert.False(sandbox.AllowsEnv("HOMEDIR"))
+	assert.False(sandbox.AllowsEnv("AWS_SECRET_ACCESS_KEY"))
+}
+
+func TestSandboxCheckFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "template-sandbox")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "root")
+	assert.Nil(os.Mkdir(root, 0755))
+	assert.Nil(ioutil.WriteFile(filepath.Join(root, "inside"), []byte("inside"), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "outside"), []byte("outside"), 0644))
+	assert.Nil(os.Symlink(filepath.Join(dir, "outside"), filepath.Join(root, "link")))
+
+	sandbox := &Sandbox{FileRoots: []string{root}}
+	assert.Nil(sandbox.CheckFile(filepath.Join(root, "inside")))
+	assert.Nil(sandbox.CheckFile(filepath.Join(root, "missing", "file")))
+	assert.NotNil(sandbox.CheckFile(filepath.Join(root, "..", "outside")))
+	assert.NotNil(sandbox.CheckFile(filepath.Join(root, "link")))
+	assert.NotNil(sandbox.CheckFile(filepath.Join(dir, "root-sibling")))
+	assert.NotNil((&Sandbox{}).CheckFile(filepath.Join(root, "inside")))
+}
+
+func TestTemplateSandboxFile(t *testing.T) {
+	assert := assert.New(t)
+
+	temp := New().WithSandbox(&Sandbox{FileRoots: []string{"testdata"}})
+
+	buffer := bytes.NewBuffer(nil)
+	err := temp.WithBody(`{{ .File "testdata/inline_file" }}`).Process(buffer)
+	assert.Nil(err)
+	assert.NotEmpty(buffer.String())
+
+	buffer.Reset()
+	err = temp.WithBody(`{{ if .HasFile "template.go" }}yep{{ else }}nope{{ end }}`).Process(buffer)
+	assert.Nil(err)
+	assert.Equal("nope", buffer.String())
+
+	err = temp.WithBody(`{{ .File "testdata/../template.go" }}`).Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	assert.Contains("template sandbox: file `testdata/../template.go` is outside the allowed roots", err.Error())
+}
+
+func TestTemplateSandboxEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	os.Setenv("SANDBOX_ALLOWED", "foo")
+	os.Setenv("SANDBOX_SECRET", "bar")
+	defer os.Unsetenv("SANDBOX_ALLOWED")
+	defer os.Unsetenv("SANDBOX_SECRET")
+
+	temp := New().WithSandbox(&Sandbox{EnvAllow: []string{"SANDBOX_ALLOWED", "SANDBOX_UNSET"}})
+
+	buffer := bytes.NewBuffer(nil)
+	err := temp.WithBody(`{{ .Env "SANDBOX_ALLOWED" }} {{ .HasEnv "SANDBOX_ALLOWED" }} {{ .HasEnv "SANDBOX_UNSET" }} {{ .Env "SANDBOX_UNSET" "default" }}`).Process(buffer)
+	assert.Nil(err)
+	assert.Equal("foo true false default", buffer.String())
+
+	for _, body := range []string{
+		`{{ .Env "SANDBOX_SECRET" }}`,
+		`{{ if .HasEnv "SANDBOX_SECRET" }}set{{ end }}`,
+	} {
+		err = temp.WithBody(body).Process(bytes.NewBuffer(nil))
+		assert.NotNil(err, body)
+		assert.Contains("template sandbox: env variable `SANDBOX_SECRET` is not allowed", err.Error())
+	}
+
+	err = temp.WithSprig().WithBody(`{{ env "SANDBOX_SECRET" }}`).Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	assert.Contains("template sandbox: env variable `SANDBOX_SECRET` is not allowed", err.Error())
+}
+
+func TestTemplateSandboxEnvDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	temp := New().WithSandbox(&Sandbox{EnvAllow: []string{"SANDBOX_ALLOWED"}})
+
+	buffer := bytes.NewBuffer(nil)
+	err := temp.WithBody(`{{ .Env "HOME" "default" }}`).Process(buffer)
+	assert.NotNil(err)
+	assert.Contains("template sandbox: env variable `HOME` is not allowed", err.Error())
+	assert.Empty(buffer.String())
+}
@@ -0,0 +1,282 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ParseSchema parses a vars schema from json or yaml.
+//
+// The schema is a subset of JSON Schema: `type`, `properties`, `required`, `items`, `enum`, `pattern` and `default`,
+// plus `sensitive` to mark values that must not appear in error messages.
+func ParseSchema(contents []byte) (*Schema, error) {
+	var schema Schema
+	if err := yaml.Unmarshal(contents, &schema); err != nil {
+		return nil, err
+	}
+	if err := schema.compile(""); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// Schema describes the expected shape of a set of vars.
+type Schema struct {
+	Type        SchemaType         `json:"type,omitempty" yaml:"type,omitempty"`
+	Description string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Default     interface{}        `json:"default,omitempty" yaml:"default,omitempty"`
+	// Sensitive marks the value, and everything nested beneath it, as sensitive; see `Sensitive`.
+	Sensitive bool `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// SchemaType is the set of types a schema allows; it unmarshals from either a single type name or a list.
+type SchemaType []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (st *SchemaType) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*st = SchemaType{single}
+		return nil
+	}
+	var multiple []string
+	if err := unmarshal(&multiple); err != nil {
+		return err
+	}
+	*st = SchemaType(multiple)
+	return nil
+}
+
+// compile validates the schema itself and compiles its patterns.
+func (s *Schema) compile(path string) error {
+	for _, typeName := range s.Type {
+		switch typeName {
+		case "string", "integer", "number", "boolean", "object", "array", "null":
+		default:
+			return fmt.Errorf("schema %s: unknown type `%s`", schemaPathName(path), typeName)
+		}
+	}
+	if len(s.Pattern) > 0 {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("schema %s: invalid pattern: %v", schemaPathName(path), err)
+		}
+		s.pattern = pattern
+	}
+	for key, property := range s.Properties {
+		if property == nil {
+			s.Properties[key] = &Schema{}
+			continue
+		}
+		if err := property.compile(joinVarPath(path, key)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// ApplyDefaults returns a copy of vars with the schema's defaults set for any missing keys.
+// Nested maps in the result are normalized to map[string]interface{}.
+func (s *Schema) ApplyDefaults(vars Vars) Vars {
+	withDefaults, _ := s.applyDefaults(normalizeValue(vars)).(map[string]interface{})
+	if withDefaults == nil {
+		withDefaults = Vars{}
+	}
+	return withDefaults
+}
+
+func (s *Schema) applyDefaults(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, property := range s.Properties {
+			if existing, hasKey := typed[key]; hasKey {
+				typed[key] = property.applyDefaults(existing)
+			} else if property.Default != nil {
+				typed[key] = property.applyDefaults(normalizeValue(property.Default))
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for index, elem := range typed {
+				typed[index] = s.Items.applyDefaults(elem)
+			}
+		}
+	}
+	return value
+}
+
+// Validate validates vars against the schema, returning a `*SchemaError` listing every violation.
+func (s *Schema) Validate(vars Vars) error {
+	var violations []SchemaViolation
+	s.validate("", normalizeValue(vars), &violations)
+	if len(violations) > 0 {
+		return &SchemaError{Violations: violations}
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, value interface{}, violations *[]SchemaViolation) {
+	violate := func(format string, args ...interface{}) {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// display is the value as it appears in messages, which is `***` for sensitive values.
+	display := value
+	if sensitive, isSensitive := value.(Sensitive); isSensitive {
+		value = sensitive.Value
+	}
+
+	valueType := schemaTypeOf(value)
+	if len(s.Type) > 0 && !s.allowsType(valueType) {
+		violate("expected %s, got %s", strings.Join(s.Type, " or "), valueType)
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		var matched bool
+		for _, allowed := range s.Enum {
+			if schemaValuesEqual(normalizeValue(allowed), value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			allowed := make([]string, len(s.Enum))
+			for index, option := range s.Enum {
+				allowed[index] = fmt.Sprintf("%v", option)
+			}
+			violate("value `%v` must be one of: %s", display, strings.Join(allowed, ", "))
+		}
+	}
+
+	if s.pattern != nil {
+		if str, isString := value.(string); isString && !s.pattern.MatchString(str) {
+			violate("value `%v` does not match pattern `%s`", display, s.Pattern)
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, hasKey := typed[key]; !hasKey {
+				*violations = append(*violations, SchemaViolation{Path: joinVarPath(path, key), Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(s.Properties))
+		for key := range s.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if elem, hasKey := typed[key]; hasKey {
+				s.Properties[key].validate(joinVarPath(path, key), elem, violations)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for index, elem := range typed {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, index), elem, violations)
+			}
+		}
+	}
+}
+
+func (s *Schema) allowsType(valueType string) bool {
+	for _, typeName := range s.Type {
+		if typeName == valueType || (typeName == "number" && valueType == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaViolation is a single way in which vars fail to match a schema.
+type SchemaViolation struct {
+	// Path is the dotted path of the offending key, e.g. `db.hosts[0]`.
+	Path    string
+	Message string
+}
+
+// SchemaError is returned when vars fail to validate against a schema.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+// Error implements error.
+func (e *SchemaError) Error() string {
+	buffer := bytes.NewBuffer(nil)
+	fmt.Fprintf(buffer, "vars do not match the schema:")
+	for _, violation := range e.Violations {
+		fmt.Fprintf(buffer, "\n\t%s: %s", schemaPathName(violation.Path), violation.Message)
+	}
+	return buffer.String()
+}
+
+func schemaPathName(path string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	return path
+}
+
+func joinVarPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+// schemaTypeOf returns the schema type name of a value.
+func schemaTypeOf(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		if number := reflect.ValueOf(value).Float(); number == float64(int64(number)) {
+			return "integer"
+		}
+		return "number"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaValuesEqual compares two values, treating numbers of different types as equal if their values are.
+func schemaValuesEqual(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	aType, bType := schemaTypeOf(a), schemaTypeOf(b)
+	isNumber := func(typeName string) bool { return typeName == "integer" || typeName == "number" }
+	if isNumber(aType) && isNumber(bType) {
+		return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
+	}
+	return false
+}
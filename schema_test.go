@@ -0,0 +1,134 @@
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testSchema = `
+type: object
+required: [name, db]
+properties:
+  name:
+    type: string
+    pattern: "^[a-z-]+$"
+  replicas:
+    type: integer
+    default: 2
+  accessibility:
+    enum: [external, internal, cluster]
+    default: internal
+  db:
+    type: object
+    required: [host]
+    properties:
+      host:
+        type: string
+      port:
+        type: [integer, string]
+        default: 5432
+  ports:
+    type: array
+    items:
+      type: integer
+`
+
+func TestParseSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	schema, err := ParseSchema([]byte(testSchema))
+	assert.Nil(err)
+	assert.Equal(SchemaType{"object"}, schema.Type)
+	assert.Equal(SchemaType{"integer", "string"}, schema.Properties["db"].Properties["port"].Type)
+
+	_, err = ParseSchema([]byte(`type: thing`))
+	assert.NotNil(err)
+	_, err = ParseSchema([]byte(`{"properties": {"name": {"pattern": "("}}}`))
+	assert.NotNil(err)
+}
+
+func TestSchemaValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	schema, err := ParseSchema([]byte(testSchema))
+	assert.Nil(err)
+
+	vars := Vars{}
+	assert.Nil(yaml.Unmarshal([]byte(`
+name: test-service
+db:
+  host: localhost
+ports: [80, 443]
+`), &vars))
+	assert.Nil(schema.Validate(vars))
+
+	vars = Vars{}
+	assert.Nil(yaml.Unmarshal([]byte(`
+name: Test_Service
+replicas: "2"
+accessibility: public
+db: {}
+ports: [80, http]
+`), &vars))
+	err = schema.Validate(vars)
+	assert.NotNil(err)
+
+	schemaErr, isSchemaErr := err.(*SchemaError)
+	assert.True(isSchemaErr)
+	assert.Equal([]SchemaViolation{
+		{Path: "accessibility", Message: "value `public` must be one of: external, internal, cluster"},
+		{Path: "db.host", Message: "is required"},
+		{Path: "name", Message: "value `Test_Service` does not match pattern `^[a-z-]+$`"},
+		{Path: "ports[1]", Message: "expected integer, got string"},
+		{Path: "replicas", Message: "expected integer, got string"},
+	}, schemaErr.Violations)
+	assert.True(strings.Contains(err.Error(), "\n\tdb.host: is required"))
+}
+
+func TestSchemaApplyDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	schema, err := ParseSchema([]byte(testSchema))
+	assert.Nil(err)
+
+	vars := Vars{
+		"name":     "test-service",
+		"replicas": 3,
+		"db":       map[interface{}]interface{}{"host": "localhost"},
+	}
+	withDefaults := schema.ApplyDefaults(vars)
+	assert.Equal(3, withDefaults["replicas"])
+	assert.Equal("internal", withDefaults["accessibility"])
+	assert.Equal(map[string]interface{}{"host": "localhost", "port": 5432}, withDefaults["db"])
+	_, hasPorts := withDefaults["ports"]
+	assert.False(hasPorts)
+
+	_, hasAccessibility := vars["accessibility"]
+	assert.False(hasAccessibility)
+}
+
+func TestTemplateWithSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	schema, err := ParseSchema([]byte(testSchema))
+	assert.Nil(err)
+
+	temp := New().
+		WithBody(`{{ .Var "name" }} {{ .Var "replicas" }} {{ .Var "db.port" }}`).
+		WithSchema(schema).
+		WithVar("name", "test-service").
+		WithVar("db", map[string]interface{}{"host": "localhost"})
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(temp.Process(buffer))
+	assert.Equal("test-service 2 5432", buffer.String())
+
+	buffer = bytes.NewBuffer(nil)
+	err = New().WithBody(`{{ .Var "name" }}`).WithSchema(schema).Process(buffer)
+	assert.NotNil(err)
+	assert.Empty(buffer.String())
+}
@@ -0,0 +1,276 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ErrSecretNotFound is returned by a `SecretProvider` that doesn't have a secret, so the next provider is tried.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretProvider looks up secrets for the `secret` template function by path, such as `db/password`.
+type SecretProvider interface {
+	Secret(path string) (string, error)
+}
+
+// SecretCache is implemented by secret providers that cache secrets. `Process` clears the caches of its
+// providers before each render, so a re-render, such as in watch mode, sees updated secrets.
+type SecretCache interface {
+	ClearCache()
+}
+
+// WithSecretProvider registers a provider for the `secret` template function.
+// Providers are tried in the order they are registered until one has the secret.
+func (t *Template) WithSecretProvider(provider SecretProvider) *Template {
+	t.secrets = append(t.secrets, provider)
+	return t
+}
+
+// secret returns a secret from the first registered provider that has it.
+func (t *Template) secret(path string) (string, error) {
+	if len(t.secrets) == 0 {
+		return "", fmt.Errorf("secret `%s`: no secret provider is registered", path)
+	}
+	for _, provider := range t.secrets {
+		value, err := t.providerSecret(provider, path)
+		if err == ErrSecretNotFound {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("secret `%s`: %v", path, err)
+		}
+		if len(value) > 0 {
+			t.secretValues = append(t.secretValues, value)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("secret `%s` not found", path)
+}
+
+// providerSecret returns a secret from a provider. Env secrets are read through the template's env variables,
+// so a sandbox applies to them as it does to `.Env`.
+func (t *Template) providerSecret(provider SecretProvider, path string) (string, error) {
+	envProvider, isEnv := provider.(EnvSecretProvider)
+	if pointer, isPointer := provider.(*EnvSecretProvider); isPointer && pointer != nil {
+		envProvider, isEnv = *pointer, true
+	}
+	if !isEnv {
+		return provider.Secret(path)
+	}
+
+	value, hasValue, err := t.lookupEnv(envProvider.EnvName(path))
+	if err != nil {
+		return "", err
+	}
+	if !hasValue {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// EnvSecretProvider reads secrets from env variables. The variable name is the prefix followed by the path
+// upper cased, with everything other than letters and digits replaced by `_`; with a prefix of `SECRET_`
+// the secret `db/password` is read from `SECRET_DB_PASSWORD`.
+type EnvSecretProvider struct {
+	Prefix string
+}
+
+// Secret implements SecretProvider.
+func (p EnvSecretProvider) Secret(path string) (string, error) {
+	if value, hasValue := os.LookupEnv(p.EnvName(path)); hasValue {
+		return value, nil
+	}
+	return "", ErrSecretNotFound
+}
+
+// EnvName returns the env variable a secret is read from.
+func (p EnvSecretProvider) EnvName(path string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, path)
+	return p.Prefix + strings.ToUpper(name)
+}
+
+// NewFileSecretProvider reads secrets from a yaml or json file encrypted with `EncryptFile`, or whose values
+// are encrypted with `EncryptVars`.
+func NewFileSecretProvider(path string, key []byte) (*FileSecretProvider, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if IsEncrypted(string(contents)) {
+		if contents, err = DecryptFile(key, contents); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	secrets := Vars{}
+	if err := yaml.Unmarshal(contents, &secrets); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if secrets, err = DecryptVars(key, secrets); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &FileSecretProvider{secrets: secrets}, nil
+}
+
+// FileSecretProvider serves secrets from a decrypted secrets file.
+// A secret's path is either a top level key, such as `db/password`, or a path through nested maps.
+type FileSecretProvider struct {
+	secrets Vars
+}
+
+// Secret implements SecretProvider.
+func (p *FileSecretProvider) Secret(path string) (string, error) {
+	value, hasValue := p.secrets[path]
+	if !hasValue {
+		var current interface{} = p.secrets
+		for _, segment := range strings.Split(path, "/") {
+			values, isMap := current.(map[string]interface{})
+			if !isMap {
+				return "", ErrSecretNotFound
+			}
+			if current, hasValue = values[segment]; !hasValue {
+				return "", ErrSecretNotFound
+			}
+		}
+		value = current
+	}
+	return secretString(value)
+}
+
+// NewVaultSecretProvider returns a provider for a vault server's key/value (version 2) secrets engine mounted at `secret`.
+func NewVaultSecretProvider(address, token string) *VaultSecretProvider {
+	return &VaultSecretProvider{Address: address, Token: token, Mount: "secret"}
+}
+
+// VaultSecretProvider reads secrets from a vault-style HTTP API. The last segment of a secret's path is the key
+// within the stored secret, so `db/password` reads the `password` key of the secret stored at `db`.
+// Each stored secret is fetched at most once per render.
+type VaultSecretProvider struct {
+	Address string
+	Token   string
+	Mount   string
+	Client  *http.Client
+
+	cacheLock sync.Mutex
+	cache     map[string]map[string]interface{}
+}
+
+// Secret implements SecretProvider.
+func (p *VaultSecretProvider) Secret(path string) (string, error) {
+	index := strings.LastIndex(path, "/")
+	if index <= 0 || index == len(path)-1 {
+		return "", fmt.Errorf("vault secrets must be in the form `<path>/<key>`")
+	}
+	data, err := p.read(path[:index])
+	if err != nil {
+		return "", err
+	}
+	value, hasValue := data[path[index+1:]]
+	if !hasValue {
+		return "", ErrSecretNotFound
+	}
+	return secretString(value)
+}
+
+// ClearCache implements SecretCache.
+func (p *VaultSecretProvider) ClearCache() {
+	p.cacheLock.Lock()
+	defer p.cacheLock.Unlock()
+	p.cache = nil
+}
+
+func (p *VaultSecretProvider) read(path string) (map[string]interface{}, error) {
+	p.cacheLock.Lock()
+	defer p.cacheLock.Unlock()
+	if data, hasData := p.cache[path]; hasData {
+		return data, nil
+	}
+
+	mount, err := escapePath(p.Mount)
+	if err != nil {
+		return nil, err
+	}
+	escapedPath, err := escapePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s", strings.TrimRight(p.Address, "/"), mount, escapedPath)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.Token)
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrSecretNotFound
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil && res.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("vault: invalid response: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		if len(body.Errors) > 0 {
+			return nil, fmt.Errorf("vault: %s: %s", res.Status, strings.Join(body.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault: %s", res.Status)
+	}
+
+	if p.cache == nil {
+		p.cache = map[string]map[string]interface{}{}
+	}
+	p.cache[path] = body.Data.Data
+	return body.Data.Data, nil
+}
+
+// secretString returns a secret value as a string; maps and lists aren't secrets.
+func secretString(value interface{}) (string, error) {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("value is not a string")
+	case nil:
+		return "", nil
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+// escapePath escapes each segment of a slash separated path for use in a URL. Empty, `.` and `..` segments,
+// including the empty segment before a leading `/`, are rejected so a path can't read from another endpoint.
+func escapePath(path string) (string, error) {
+	segments := strings.Split(path, "/")
+	for index, segment := range segments {
+		if len(segment) == 0 || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid vault path `%s`; segments cannot be empty, `.` or `..`", path)
+		}
+		segments[index] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/"), nil
+}
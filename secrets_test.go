@@ -0,0 +1,218 @@
+package template
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestTemplateSecret(t *testing.T) {
+	assert := assert.New(t)
+
+	os.Setenv("TEST_SECRET_DB_PASSWORD", "hunter2")
+	defer os.Unsetenv("TEST_SECRET_DB_PASSWORD")
+
+	buffer := bytes.NewBuffer(nil)
+	err := New().
+		WithBody(`{{ secret "db/password" }}`).
+		WithSecretProvider(EnvSecretProvider{Prefix: "TEST_SECRET_"}).
+		Process(buffer)
+	assert.Nil(err)
+	assert.Equal("hunter2", buffer.String())
+
+	err = New().WithBody(`{{ secret "db/password" }}`).Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	assert.Contains("no secret provider is registered", err.Error())
+
+	err = New().
+		WithBody(`{{ secret "db/username" }}`).
+		WithSecretProvider(EnvSecretProvider{Prefix: "TEST_SECRET_"}).
+		Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	assert.Contains("secret `db/username` not found", err.Error())
+}
+
+func TestTemplateSecretSandbox(t *testing.T) {
+	assert := assert.New(t)
+
+	os.Setenv("TEST_SECRET_DB_PASSWORD", "hunter2")
+	defer os.Unsetenv("TEST_SECRET_DB_PASSWORD")
+
+	temp := New().
+		WithBody(`{{ secret "db/password" }}`).
+		WithSecretProvider(EnvSecretProvider{Prefix: "TEST_SECRET_"}).
+		WithSandbox(&Sandbox{EnvAllow: []string{"APP_*"}})
+	err := temp.Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	assert.Contains("template sandbox: env variable `TEST_SECRET_DB_PASSWORD` is not allowed", err.Error())
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(temp.WithSandbox(&Sandbox{EnvAllow: []string{"TEST_SECRET_*"}}).Process(buffer))
+	assert.Equal("hunter2", buffer.String())
+}
+
+func TestEnvSecretProviderEnvName(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("SECRET_DB_PASSWORD", EnvSecretProvider{Prefix: "SECRET_"}.EnvName("db/password"))
+	assert.Equal("API_KEY", EnvSecretProvider{}.EnvName("api-key"))
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := ParseKey(Helpers{}.CreateKey(32))
+	assert.Nil(err)
+	encrypted, err := EncryptFile(key, []byte("db/password: hunter2\napi:\n  key: abc123\n  tokens: [a, b]\n"))
+	assert.Nil(err)
+
+	dir, err := ioutil.TempDir("", "template-secrets")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secrets.enc.yml")
+	assert.Nil(ioutil.WriteFile(path, encrypted, 0600))
+
+	provider, err := NewFileSecretProvider(path, key)
+	assert.Nil(err)
+
+	value, err := provider.Secret("db/password")
+	assert.Nil(err)
+	assert.Equal("hunter2", value)
+
+	value, err = provider.Secret("api/key")
+	assert.Nil(err)
+	assert.Equal("abc123", value)
+
+	_, err = provider.Secret("api/missing")
+	assert.Equal(ErrSecretNotFound, err)
+
+	_, err = provider.Secret("api/tokens")
+	assert.NotNil(err)
+
+	otherKey, err := ParseKey(Helpers{}.CreateKey(32))
+	assert.Nil(err)
+	_, err = NewFileSecretProvider(path, otherKey)
+	assert.NotNil(err)
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.Header.Get("X-Vault-Token") != "test-token" {
+			rw.WriteHeader(http.StatusForbidden)
+			rw.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		if req.URL.Path != "/v1/secret/data/services/db" {
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"errors": []}`))
+			return
+		}
+		rw.Write([]byte(`{"data": {"data": {"password": "hunter2"}, "metadata": {"version": 1}}}`))
+	}))
+	defer server.Close()
+
+	provider := NewVaultSecretProvider(server.URL, "test-token")
+
+	buffer := bytes.NewBuffer(nil)
+	err := New().
+		WithBody(`{{ secret "services/db/password" }} {{ secret "services/db/password" }}`).
+		WithSecretProvider(provider).
+		Process(buffer)
+	assert.Nil(err)
+	assert.Equal("hunter2 hunter2", buffer.String())
+	assert.Equal(1, requests)
+
+	_, err = provider.Secret("services/db/username")
+	assert.Equal(ErrSecretNotFound, err)
+
+	_, err = provider.Secret("services/cache/password")
+	assert.Equal(ErrSecretNotFound, err)
+
+	_, err = provider.Secret("password")
+	assert.NotNil(err)
+
+	_, err = NewVaultSecretProvider(server.URL, "wrong-token").Secret("services/db/password")
+	assert.NotNil(err)
+	assert.Contains("permission denied", err.Error())
+}
+
+func TestVaultSecretProviderEscapesPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.EscapedPath())
+		if req.URL.Path != "/v1/secret/data/services/db?#%" || len(req.URL.RawQuery) > 0 {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Write([]byte(`{"data": {"data": {"password": "hunter2"}}}`))
+	}))
+	defer server.Close()
+
+	value, err := NewVaultSecretProvider(server.URL, "test-token").Secret("services/db?#%/password")
+	assert.Nil(err)
+	assert.Equal("hunter2", value)
+	assert.Equal([]string{"/v1/secret/data/services/db%3F%23%25"}, paths)
+}
+
+func TestVaultSecretProviderRejectsInvalidPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.EscapedPath())
+		rw.Write([]byte(`{"data": {"data": {"password": "hunter2"}}}`))
+	}))
+	defer server.Close()
+
+	provider := NewVaultSecretProvider(server.URL, "test-token")
+	for _, path := range []string{
+		"/services/db/password",
+		"services//db/password",
+		"services/./db/password",
+		"services/../../sys/password",
+		"../password",
+	} {
+		_, err := provider.Secret(path)
+		assert.NotNil(err, path)
+		assert.Contains("invalid vault path", err.Error())
+	}
+
+	provider.Mount = "secret/.."
+	_, err := provider.Secret("services/db/password")
+	assert.NotNil(err)
+	assert.Empty(paths)
+}
+
+func TestVaultSecretProviderClearsCacheEachRender(t *testing.T) {
+	assert := assert.New(t)
+
+	password := "hunter2"
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"data": {"data": {"password": "` + password + `"}}}`))
+	}))
+	defer server.Close()
+
+	temp := New().
+		WithBody(`{{ secret "services/db/password" }}`).
+		WithSecretProvider(NewVaultSecretProvider(server.URL, "test-token"))
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(temp.Process(buffer))
+	assert.Equal("hunter2", buffer.String())
+
+	password = "correct-horse"
+	buffer = bytes.NewBuffer(nil)
+	assert.Nil(temp.Process(buffer))
+	assert.Equal("correct-horse", buffer.String())
+}
@@ -2,6 +2,8 @@ package template
 
 import (
 	"bytes"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
@@ -9,17 +11,52 @@ import (
 	"strings"
 )
 
-// NewSemver creates a new Semver.
-func NewSemver(version string) (*Semver, error) {
+// SemverMode is how strictly `NewSemver` parses a version.
+type SemverMode int
+
+const (
+	// SemverDefault parses versions in dotted-tri format the way `Set` does, accepting leading zeros and
+	// any pre-release or metadata.
+	SemverDefault SemverMode = iota
+	// SemverStrict enforces the SemVer 2.0.0 grammar, returning a `*SemverError` pointing at the first violation.
+	SemverStrict
+	// SemverLenient also accepts surrounding spaces, a `v` prefix and missing minor or patch versions,
+	// normalizing e.g. `v1.2` to `1.2.0` and `1` to `1.0.0`.
+	SemverLenient
+)
+
+// NewSemver creates a new Semver. It can be given a mode to parse the version strictly or leniently.
+func NewSemver(version string, mode ...SemverMode) (*Semver, error) {
 	v := Semver{}
 
-	if err := v.Set(version); err != nil {
+	var err error
+	switch {
+	case len(mode) > 0 && mode[0] == SemverStrict:
+		v, err = parseStrictSemver(version)
+	case len(mode) > 0 && mode[0] == SemverLenient:
+		v, err = parseLenientSemver(version)
+	default:
+		err = v.Set(version)
+	}
+	if err != nil {
 		return nil, err
 	}
 
 	return &v, nil
 }
 
+// SemverError is returned when a version can't be parsed strictly or leniently.
+type SemverError struct {
+	Version string
+	// Offset is the zero-based byte offset of the problem in the version.
+	Offset int
+	Reason string
+}
+
+func (e *SemverError) Error() string {
+	return fmt.Sprintf("invalid version `%s` at offset %d: %s", e.Version, e.Offset, e.Reason)
+}
+
 // Semver is a semantic version
 type Semver struct {
 	Major      int64
@@ -57,6 +94,138 @@ func (v *Semver) Set(version string) error {
 	return nil
 }
 
+// parseStrictSemver parses a version following the SemVer 2.0.0 grammar.
+func parseStrictSemver(version string) (Semver, error) {
+	v := Semver{}
+	fail := func(offset int, format string, args ...interface{}) (Semver, error) {
+		return Semver{}, &SemverError{Version: version, Offset: offset, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	pos := 0
+	for index, name := range []string{"major", "minor", "patch"} {
+		if index > 0 {
+			if pos >= len(version) || version[pos] != '.' {
+				return fail(pos, "expected `.` before the %s version", name)
+			}
+			pos++
+		}
+		start := pos
+		for pos < len(version) && isSemverDigit(version[pos]) {
+			pos++
+		}
+		if start == pos {
+			return fail(pos, "expected the %s version", name)
+		}
+		if version[start] == '0' && pos-start > 1 {
+			return fail(start, "the %s version has a leading zero", name)
+		}
+		val, err := strconv.ParseInt(version[start:pos], 10, 64)
+		if err != nil {
+			return fail(start, "the %s version is out of range", name)
+		}
+		switch index {
+		case 0:
+			v.Major = val
+		case 1:
+			v.Minor = val
+		case 2:
+			v.Patch = val
+		}
+	}
+
+	if pos < len(version) && version[pos] == '-' {
+		pos++
+		start := pos
+		end, err := scanSemverIdentifiers(version, pos, "pre-release")
+		if err != nil {
+			return Semver{}, err
+		}
+		v.PreRelease = PreRelease(version[start:end])
+		pos = end
+	}
+
+	if pos < len(version) && version[pos] == '+' {
+		pos++
+		start := pos
+		end, err := scanSemverIdentifiers(version, pos, "metadata")
+		if err != nil {
+			return Semver{}, err
+		}
+		v.Metadata = version[start:end]
+		pos = end
+	}
+
+	if pos < len(version) {
+		return fail(pos, "unexpected character %q", version[pos])
+	}
+	return v, nil
+}
+
+// scanSemverIdentifiers scans dot separated pre-release or metadata identifiers starting at pos,
+// returning the offset just past them.
+func scanSemverIdentifiers(version string, pos int, kind string) (int, error) {
+	for {
+		start := pos
+		numeric := true
+		for pos < len(version) && (isSemverDigit(version[pos]) || isSemverLetter(version[pos])) {
+			numeric = numeric && isSemverDigit(version[pos])
+			pos++
+		}
+		if start == pos {
+			return pos, &SemverError{Version: version, Offset: pos, Reason: fmt.Sprintf("empty %s identifier", kind)}
+		}
+		if kind == "pre-release" && numeric && version[start] == '0' && pos-start > 1 {
+			return pos, &SemverError{Version: version, Offset: start, Reason: "numeric pre-release identifier has a leading zero"}
+		}
+		if pos >= len(version) || version[pos] != '.' {
+			return pos, nil
+		}
+		pos++
+	}
+}
+
+func isSemverDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSemverLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '-'
+}
+
+// parseLenientSemver parses a version that may have surrounding spaces, a `v` prefix or be missing its minor
+// or patch versions.
+func parseLenientSemver(version string) (Semver, error) {
+	core := strings.TrimSpace(version)
+	offset := strings.Index(version, core)
+	if strings.HasPrefix(core, "v") || strings.HasPrefix(core, "V") {
+		core = core[1:]
+		offset++
+	}
+	metadata := splitOff(&core, "+")
+	preRelease := splitOff(&core, "-")
+
+	parsed := make([]int64, 3, 3)
+	for i, part := range strings.Split(core, ".") {
+		if i > 2 {
+			return Semver{}, &SemverError{Version: version, Offset: offset - 1, Reason: "expected at most three version numbers"}
+		}
+		val, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return Semver{}, &SemverError{Version: version, Offset: offset, Reason: fmt.Sprintf("expected the %s version", []string{"major", "minor", "patch"}[i])}
+		}
+		parsed[i] = val
+		offset += len(part) + 1
+	}
+
+	return Semver{
+		Major:      parsed[0],
+		Minor:      parsed[1],
+		Patch:      parsed[2],
+		PreRelease: PreRelease(preRelease),
+		Metadata:   metadata,
+	}, nil
+}
+
 func (v Semver) String() string {
 	var buffer bytes.Buffer
 
@@ -99,6 +268,43 @@ func (v *Semver) UnmarshalJSON(data []byte) error {
 	return v.Set(string(data[1 : l-1]))
 }
 
+// MarshalYAML marshals the semver to yaml.
+func (v Semver) MarshalYAML() (interface{}, error) {
+	return v.String(), nil
+}
+
+// MarshalText marshals the semver to text. Implements encoding.TextMarshaler
+func (v Semver) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText unmarshals the semver from text. Implements encoding.TextUnmarshaler
+func (v *Semver) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return v.Set(string(data))
+}
+
+// Scan reads the semver from a database column. Implements sql.Scanner
+func (v *Semver) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		*v = Semver{}
+		return nil
+	case string:
+		return v.Set(data)
+	case []byte:
+		return v.Set(string(data))
+	}
+	return fmt.Errorf("cannot scan %T into a semver", src)
+}
+
+// Value writes the semver to a database column. Implements driver.Valuer
+func (v Semver) Value() (driver.Value, error) {
+	return v.String(), nil
+}
+
 // Compare tests if v is less than, equal to, or greater than versionB,
 // returning -1, 0, or +1 respectively.
 func (v Semver) Compare(versionB Semver) int {
@@ -147,6 +353,53 @@ func (v *Semver) BumpPatch() {
 	v.Metadata = ""
 }
 
+// BumpPreRelease advances the pre-release with the given prefix, e.g. `rc.1` to `rc.2`, and clears the metadata.
+// A pre-release with a different prefix is replaced with `<prefix>.1`, and a release is bumped to the
+// `<prefix>.1` pre-release of the next patch version. An empty prefix keeps the current one.
+// The result is always higher, so switching to a prefix that sorts lower, e.g. from `rc` to `beta`,
+// also moves to the next patch version.
+func (v *Semver) BumpPreRelease(prefix string) {
+	current := *v
+	next := 1
+	if len(v.PreRelease) == 0 {
+		v.Patch++
+	} else {
+		base, number := v.PreRelease.split()
+		if len(prefix) == 0 {
+			prefix = base
+		}
+		if base == prefix {
+			next = number + 1
+		}
+	}
+
+	v.PreRelease = nextPreRelease(prefix, next)
+	v.Metadata = ""
+	if !current.LessThan(*v) {
+		v.Patch++
+		v.PreRelease = nextPreRelease(prefix, 1)
+	}
+}
+
+func nextPreRelease(prefix string, number int) PreRelease {
+	if len(prefix) == 0 {
+		return PreRelease(strconv.Itoa(number))
+	}
+	return PreRelease(fmt.Sprintf("%s.%d", prefix, number))
+}
+
+// Finalize clears the PreRelease and Metadata fields, e.g. turning `1.2.3-rc.2` into the release `1.2.3`.
+func (v *Semver) Finalize() {
+	v.PreRelease = PreRelease("")
+	v.Metadata = ""
+}
+
+// WithMetadata sets the Metadata field and returns a reference to the semver.
+func (v *Semver) WithMetadata(metadata string) *Semver {
+	v.Metadata = metadata
+	return v
+}
+
 func preReleaseCompare(versionA Semver, versionB Semver) int {
 	a := versionA.PreRelease
 	b := versionB.PreRelease
@@ -244,6 +497,21 @@ func (p PreRelease) Slice() []string {
 	return strings.Split(preRelease, ".")
 }
 
+// split splits a pre-release into its prefix and trailing number, e.g. `rc.2` into `rc` and 2.
+// The number is 0 if there isn't one.
+func (p PreRelease) split() (string, int) {
+	preRelease := string(p)
+	lastDot := strings.LastIndex(preRelease, ".")
+	number, err := strconv.Atoi(preRelease[lastDot+1:])
+	if err != nil || number < 0 {
+		return preRelease, 0
+	}
+	if lastDot < 0 {
+		return "", number
+	}
+	return preRelease[:lastDot], number
+}
+
 func splitOff(input *string, delim string) (val string) {
 	parts := strings.SplitN(*input, delim, 2)
 
@@ -274,3 +542,397 @@ func (s Semvers) Less(i, j int) bool {
 func Sort(versions []*Semver) {
 	sort.Sort(Semvers(versions))
 }
+
+// Constraint is a range of semantic versions, written the way npm and Cargo write them:
+//
+//	1.2.3, =1.2.3       exactly 1.2.3
+//	>1.2.3, >=1.2.3     greater than, or at least, 1.2.3; likewise <, <= and !=
+//	1.2, 1.2.x, 1.2.*   any 1.2 version; missing or wildcard parts match anything
+//	~1.2.3              >=1.2.3 <1.3.0, so only patch updates
+//	^1.2.3              >=1.2.3 <2.0.0, so updates that keep the left-most non-zero part
+//	1.2.3 - 2.3         >=1.2.3 <2.4.0
+//	*                   any version
+//
+// Clauses separated by spaces or commas must all hold, and alternatives are separated by `||`.
+// A pre-release version only satisfies an alternative that names a pre-release of the same major,
+// minor and patch version, so `^1.2.0` doesn't match `2.0.0-rc.1`.
+type Constraint struct {
+	source       string
+	alternatives [][]constraintClause
+}
+
+// NewConstraint parses a new Constraint.
+func NewConstraint(constraint string) (*Constraint, error) {
+	c := Constraint{}
+
+	if err := c.Set(constraint); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Set parses and updates c from the given constraint string. Implements flag.Value
+func (c *Constraint) Set(constraint string) error {
+	var alternatives [][]constraintClause
+	for _, alternative := range strings.Split(constraint, "||") {
+		tokens := strings.Fields(strings.Replace(alternative, ",", " ", -1))
+		if len(tokens) == 0 {
+			return fmt.Errorf("invalid constraint `%s`: empty alternative", constraint)
+		}
+
+		var clauses []constraintClause
+		for index := 0; index < len(tokens); index++ {
+			var clause constraintClause
+			var err error
+			if isConstraintOperator(tokens[index]) && index+1 < len(tokens) {
+				clause, err = parseConstraintClause(tokens[index] + tokens[index+1])
+				index++
+			} else if index+2 < len(tokens) && tokens[index+1] == "-" {
+				clause, err = parseHyphenRange(tokens[index], tokens[index+2])
+				index += 2
+			} else {
+				clause, err = parseConstraintClause(tokens[index])
+			}
+			if err != nil {
+				return fmt.Errorf("invalid constraint `%s`: %v", constraint, err)
+			}
+			clauses = append(clauses, clause)
+		}
+		alternatives = append(alternatives, clauses)
+	}
+
+	c.source = strings.TrimSpace(constraint)
+	c.alternatives = alternatives
+	return nil
+}
+
+func (c Constraint) String() string {
+	return c.source
+}
+
+// Check tests if a version satisfies the constraint. A nil version never does.
+func (c Constraint) Check(version *Semver) bool {
+	return c.Validate(version) == nil
+}
+
+// Validate returns an error explaining which clause of each alternative the version fails, or nil if it satisfies the constraint.
+func (c Constraint) Validate(version *Semver) error {
+	if version == nil {
+		return fmt.Errorf("a nil version does not satisfy `%s`", c.source)
+	}
+	var failures []string
+	for _, clauses := range c.alternatives {
+		failure := checkConstraintClauses(clauses, *version)
+		if len(failure) == 0 {
+			return nil
+		}
+		failures = append(failures, failure)
+	}
+	return fmt.Errorf("%s does not satisfy `%s`: %s", version, c.source, strings.Join(failures, "; "))
+}
+
+// MaxSatisfying returns the highest of the versions that satisfies the constraint, or nil if none do.
+// Nil versions are skipped.
+func (c Constraint) MaxSatisfying(versions Semvers) *Semver {
+	var max *Semver
+	for _, version := range versions {
+		if c.Check(version) && (max == nil || max.LessThan(*version)) {
+			max = version
+		}
+	}
+	return max
+}
+
+// UnmarshalYAML unmarshals a constraint from yaml.
+func (c *Constraint) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var data string
+	if err := unmarshal(&data); err != nil {
+		return err
+	}
+	return c.Set(data)
+}
+
+// MarshalYAML marshals the constraint to yaml.
+func (c Constraint) MarshalYAML() (interface{}, error) {
+	return c.String(), nil
+}
+
+// MarshalJSON marshals the constraint to json.
+func (c Constraint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON unmarshals the constraint from json.
+func (c *Constraint) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var constraint string
+	if err := json.Unmarshal(data, &constraint); err != nil {
+		return errors.New("invalid constraint string")
+	}
+	if len(constraint) == 0 {
+		return nil
+	}
+	return c.Set(constraint)
+}
+
+// constraintClause is a single clause of a constraint, e.g. `^1.2`, expanded into the comparisons that must all hold.
+type constraintClause struct {
+	source      string
+	comparisons []semverComparison
+	negate      bool
+}
+
+// semverComparison compares a version against a bound with an operator.
+type semverComparison struct {
+	operator string
+	version  Semver
+}
+
+var constraintOperators = []string{">=", "<=", "!=", ">", "<", "=", "~", "^"}
+
+func isConstraintOperator(token string) bool {
+	for _, operator := range constraintOperators {
+		if token == operator {
+			return true
+		}
+	}
+	return false
+}
+
+func parseConstraintClause(source string) (constraintClause, error) {
+	operator := ""
+	version := source
+	for _, candidate := range constraintOperators {
+		if strings.HasPrefix(source, candidate) {
+			operator = candidate
+			version = strings.TrimPrefix(source, candidate)
+			break
+		}
+	}
+
+	bound, parts, err := parsePartialSemver(version)
+	if err != nil {
+		return constraintClause{}, err
+	}
+	if parts == 0 && operator != "" && operator != "=" && operator != ">=" && operator != "~" && operator != "^" {
+		return constraintClause{}, fmt.Errorf("`%s` can't be used with a wildcard", operator)
+	}
+
+	clause := constraintClause{source: source}
+	switch operator {
+	case "", "=":
+		clause.comparisons = partialComparisons(bound, parts)
+	case "!=":
+		clause.comparisons = partialComparisons(bound, parts)
+		clause.negate = true
+	case ">":
+		if parts < 3 {
+			clause.comparisons = []semverComparison{{">=", nextPartialSemver(bound, parts)}}
+		} else {
+			clause.comparisons = []semverComparison{{">", bound}}
+		}
+	case ">=":
+		clause.comparisons = []semverComparison{{">=", bound}}
+	case "<":
+		clause.comparisons = []semverComparison{{"<", bound}}
+	case "<=":
+		if parts < 3 {
+			clause.comparisons = []semverComparison{{"<", nextPartialSemver(bound, parts)}}
+		} else {
+			clause.comparisons = []semverComparison{{"<=", bound}}
+		}
+	case "~":
+		clause.comparisons = []semverComparison{{">=", bound}}
+		if parts == 1 {
+			clause.comparisons = append(clause.comparisons, semverComparison{"<", Semver{Major: bound.Major + 1}})
+		} else if parts > 1 {
+			clause.comparisons = append(clause.comparisons, semverComparison{"<", Semver{Major: bound.Major, Minor: bound.Minor + 1}})
+		}
+	case "^":
+		clause.comparisons = []semverComparison{{">=", bound}}
+		if bound.Major > 0 || parts == 1 {
+			clause.comparisons = append(clause.comparisons, semverComparison{"<", Semver{Major: bound.Major + 1}})
+		} else if bound.Minor > 0 || parts == 2 {
+			clause.comparisons = append(clause.comparisons, semverComparison{"<", Semver{Minor: bound.Minor + 1}})
+		} else if parts == 3 {
+			clause.comparisons = append(clause.comparisons, semverComparison{"<", Semver{Patch: bound.Patch + 1}})
+		}
+	}
+	return clause, nil
+}
+
+// parseHyphenRange parses `lower - upper`; a partial upper bound includes every version it matches.
+func parseHyphenRange(lower, upper string) (constraintClause, error) {
+	lowerBound, _, err := parsePartialSemver(lower)
+	if err != nil {
+		return constraintClause{}, err
+	}
+	upperBound, parts, err := parsePartialSemver(upper)
+	if err != nil {
+		return constraintClause{}, err
+	}
+
+	clause := constraintClause{
+		source:      lower + " - " + upper,
+		comparisons: []semverComparison{{">=", lowerBound}},
+	}
+	if parts == 3 {
+		clause.comparisons = append(clause.comparisons, semverComparison{"<=", upperBound})
+	} else if parts > 0 {
+		clause.comparisons = append(clause.comparisons, semverComparison{"<", nextPartialSemver(upperBound, parts)})
+	}
+	return clause, nil
+}
+
+// parsePartialSemver parses a version that may be missing parts or use `x`, `X` or `*` wildcards for them,
+// returning the lowest version it matches and how many parts are given.
+func parsePartialSemver(version string) (Semver, int, error) {
+	source := version
+	version = strings.TrimPrefix(version, "v")
+	hasMetadata := strings.Contains(version, "+")
+	metadata := splitOff(&version, "+")
+	hasPreRelease := strings.Contains(version, "-")
+	preRelease := splitOff(&version, "-")
+	if hasPreRelease && !validSemverIdentifiers(preRelease, "pre-release") {
+		return Semver{}, 0, fmt.Errorf("%s has an invalid pre-release", source)
+	}
+	if hasMetadata && !validSemverIdentifiers(metadata, "metadata") {
+		return Semver{}, 0, fmt.Errorf("%s has invalid metadata", source)
+	}
+
+	if len(version) == 0 {
+		return Semver{}, 0, fmt.Errorf("missing version")
+	}
+	dotParts := strings.Split(version, ".")
+	if len(dotParts) > 3 {
+		return Semver{}, 0, fmt.Errorf("%s is not a version", source)
+	}
+
+	parsed := make([]int64, 3, 3)
+	parts := 0
+	for i, part := range dotParts {
+		if part == "x" || part == "X" || part == "*" {
+			continue
+		}
+		val, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || i > parts {
+			return Semver{}, 0, fmt.Errorf("%s is not a version", source)
+		}
+		parsed[i] = val
+		parts++
+	}
+	if len(preRelease) > 0 && parts < 3 {
+		return Semver{}, 0, fmt.Errorf("%s has a pre-release but not a full version", source)
+	}
+
+	return Semver{Major: parsed[0], Minor: parsed[1], Patch: parsed[2], PreRelease: PreRelease(preRelease)}, parts, nil
+}
+
+// validSemverIdentifiers returns if a whole string is dot separated pre-release or metadata identifiers,
+// checked as strictly as in a version.
+func validSemverIdentifiers(identifiers, kind string) bool {
+	end, err := scanSemverIdentifiers(identifiers, 0, kind)
+	return err == nil && end == len(identifiers)
+}
+
+// partialComparisons returns the comparisons matching every version a partial version matches.
+func partialComparisons(bound Semver, parts int) []semverComparison {
+	switch parts {
+	case 0:
+		return nil
+	case 3:
+		return []semverComparison{{"=", bound}}
+	}
+	return []semverComparison{{">=", bound}, {"<", nextPartialSemver(bound, parts)}}
+}
+
+// nextPartialSemver returns the lowest version a partial version doesn't match, e.g. 1.3.0 for 1.2.
+func nextPartialSemver(bound Semver, parts int) Semver {
+	if parts == 1 {
+		return Semver{Major: bound.Major + 1}
+	}
+	return Semver{Major: bound.Major, Minor: bound.Minor + 1}
+}
+
+// checkConstraintClauses returns why a version fails an alternative, or nothing if it satisfies it.
+func checkConstraintClauses(clauses []constraintClause, version Semver) string {
+	for _, clause := range clauses {
+		if !clause.check(version) {
+			return fmt.Sprintf("%s is not %s", version, clause.source)
+		}
+	}
+	if len(version.PreRelease) == 0 {
+		return ""
+	}
+	for _, clause := range clauses {
+		for _, comparison := range clause.comparisons {
+			if len(comparison.version.PreRelease) > 0 && recursiveCompare(comparison.version.Slice(), version.Slice()) == 0 {
+				return ""
+			}
+		}
+	}
+	return fmt.Sprintf("%s is a pre-release and no clause names a pre-release of %d.%d.%d", version, version.Major, version.Minor, version.Patch)
+}
+
+func (c constraintClause) check(version Semver) bool {
+	satisfied := true
+	for _, comparison := range c.comparisons {
+		if !comparison.check(version) {
+			satisfied = false
+			break
+		}
+	}
+	return satisfied != c.negate
+}
+
+func (c semverComparison) check(version Semver) bool {
+	cmp := version.Compare(c.version)
+	switch c.operator {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	}
+	return cmp == 0
+}
+
+// toSemver converts a version string, Semver or *Semver into a new *Semver.
+func toSemver(value interface{}) (*Semver, error) {
+	switch version := value.(type) {
+	case *Semver:
+		if version == nil {
+			return nil, fmt.Errorf("input must be a version")
+		}
+		copied := *version
+		return &copied, nil
+	case Semver:
+		return &version, nil
+	case string:
+		return NewSemver(version)
+	}
+	return nil, fmt.Errorf("input must be a version")
+}
+
+// toSemvers converts a list of version strings or Semvers into Semvers.
+func toSemvers(collection interface{}) (Semvers, error) {
+	elems, err := toSlice(collection)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(Semvers, len(elems))
+	for index, elem := range elems {
+		if versions[index], err = toSemver(elem); err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
@@ -1,9 +1,11 @@
 package template
 
 import (
+	"encoding/json"
 	"testing"
 
 	assert "github.com/blendlabs/go-assert"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestSemver(t *testing.T) {
@@ -16,3 +18,254 @@ func TestSemver(t *testing.T) {
 	assert.Equal(3, sv.Patch)
 	assert.Equal("beta1", sv.PreRelease)
 }
+
+func TestSemverStrict(t *testing.T) {
+	assert := assert.New(t)
+
+	sv, err := NewSemver("1.2.3-rc.1.x-y+build.001", SemverStrict)
+	assert.Nil(err)
+	assert.Equal("1.2.3-rc.1.x-y+build.001", sv.String())
+
+	for version, expected := range map[string]string{
+		"01.2.3":        "invalid version `01.2.3` at offset 0: the major version has a leading zero",
+		"1.02.3":        "invalid version `1.02.3` at offset 2: the minor version has a leading zero",
+		"1.2":           "invalid version `1.2` at offset 3: expected `.` before the patch version",
+		"v1.2.3":        "invalid version `v1.2.3` at offset 0: expected the major version",
+		"1.2.3-":        "invalid version `1.2.3-` at offset 6: empty pre-release identifier",
+		"1.2.3-rc..1":   "invalid version `1.2.3-rc..1` at offset 9: empty pre-release identifier",
+		"1.2.3-rc.01":   "invalid version `1.2.3-rc.01` at offset 9: numeric pre-release identifier has a leading zero",
+		"1.2.3+build_1": "invalid version `1.2.3+build_1` at offset 11: unexpected character '_'",
+		"1.2.3.4":       "invalid version `1.2.3.4` at offset 5: unexpected character '.'",
+	} {
+		_, err := NewSemver(version, SemverStrict)
+		assert.NotNil(err, version)
+		_, isSemverError := err.(*SemverError)
+		assert.True(isSemverError)
+		assert.Equal(expected, err.Error())
+	}
+}
+
+func TestSemverLenient(t *testing.T) {
+	assert := assert.New(t)
+
+	for version, expected := range map[string]string{
+		"v1.2":           "1.2.0",
+		"1":              "1.0.0",
+		" V1.02.3 ":      "1.2.3",
+		"v2-beta.1+sha1": "2.0.0-beta.1+sha1",
+	} {
+		sv, err := NewSemver(version, SemverLenient)
+		assert.Nil(err, version)
+		assert.Equal(expected, sv.String())
+	}
+
+	_, err := NewSemver("v1.x", SemverLenient)
+	assert.NotNil(err)
+	assert.Equal("invalid version `v1.x` at offset 3: expected the minor version", err.Error())
+	_, err = NewSemver("1.2.3.4", SemverLenient)
+	assert.NotNil(err)
+
+	_, err = NewSemver("1.2", SemverDefault)
+	assert.NotNil(err)
+}
+
+func TestSemverBumpPreRelease(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range []struct {
+		version, prefix, expected string
+	}{
+		{"1.2.3-rc.1", "rc", "1.2.3-rc.2"},
+		{"1.2.3-rc.9+sha1", "", "1.2.3-rc.10"},
+		{"1.2.3-rc", "rc", "1.2.3-rc.1"},
+		{"1.2.3-beta.3", "rc", "1.2.3-rc.1"},
+		{"1.2.3-rc.1", "beta", "1.2.4-beta.1"},
+		{"1.2.3-rc.1", "rc.x", "1.2.3-rc.x.1"},
+		{"1.2.3-rc.1", "1", "1.2.4-1.1"},
+		{"1.2.3-4", "", "1.2.3-5"},
+		{"1.2.3", "rc", "1.2.4-rc.1"},
+		{"1.2.3", "", "1.2.4-1"},
+	} {
+		sv, err := NewSemver(test.version)
+		assert.Nil(err)
+		sv.BumpPreRelease(test.prefix)
+		assert.Equal(test.expected, sv.String(), test.version)
+	}
+}
+
+func TestSemverFinalizeAndMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	sv, err := NewSemver("1.2.3-rc.2+sha1")
+	assert.Nil(err)
+	sv.Finalize()
+	assert.Equal("1.2.3", sv.String())
+	assert.Equal("1.2.3+build.7", sv.WithMetadata("build.7").String())
+}
+
+func TestSemverMarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	type release struct {
+		Version Semver `json:"version" yaml:"version"`
+	}
+
+	var fromYAML release
+	assert.Nil(yaml.Unmarshal([]byte("version: 1.2.3-rc.1\n"), &fromYAML))
+	data, err := yaml.Marshal(fromYAML)
+	assert.Nil(err)
+	assert.Equal("version: 1.2.3-rc.1\n", string(data))
+
+	var fromJSON release
+	assert.Nil(json.Unmarshal([]byte(`{"version":"2.0.0+sha1"}`), &fromJSON))
+	data, err = json.Marshal(fromJSON)
+	assert.Nil(err)
+	assert.Equal(`{"version":"2.0.0+sha1"}`, string(data))
+
+	var fromText Semver
+	assert.Nil(fromText.UnmarshalText([]byte("1.4.0")))
+	text, err := fromText.MarshalText()
+	assert.Nil(err)
+	assert.Equal("1.4.0", string(text))
+	assert.NotNil(fromText.UnmarshalText([]byte("1.4")))
+}
+
+func TestSemverSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	var sv Semver
+	assert.Nil(sv.Scan([]byte("1.2.3-rc.1")))
+	assert.Equal("1.2.3-rc.1", sv.String())
+	value, err := sv.Value()
+	assert.Nil(err)
+	assert.Equal("1.2.3-rc.1", value)
+
+	assert.Nil(sv.Scan("2.0.0"))
+	assert.Equal("2.0.0", sv.String())
+	assert.Nil(sv.Scan(nil))
+	assert.Equal("0.0.0", sv.String())
+	assert.NotNil(sv.Scan(123))
+}
+
+func TestConstraint(t *testing.T) {
+	assert := assert.New(t)
+
+	for constraint, cases := range map[string]map[string]bool{
+		">=1.2.0 <2.0.0 || 3.0.0": {"1.1.9": false, "1.2.0": true, "1.9.9": true, "2.0.0": false, "3.0.0": true, "3.0.1": false},
+		">= 1.0.0, != 1.5.0":      {"0.9.0": false, "1.0.0": true, "1.5.0": false, "1.5.1": true},
+		"^1.2":                    {"1.1.9": false, "1.2.0": true, "1.9.0": true, "2.0.0": false, "2.0.0-rc.1": false},
+		"^0.2.3":                  {"0.2.2": false, "0.2.3": true, "0.2.9": true, "0.3.0": false},
+		"^0.0.3":                  {"0.0.3": true, "0.0.4": false},
+		"~1.2.3":                  {"1.2.2": false, "1.2.3": true, "1.2.9": true, "1.3.0": false},
+		"~1":                      {"1.0.0": true, "1.9.9": true, "2.0.0": false},
+		"1.2.x":                   {"1.1.9": false, "1.2.0": true, "1.2.7": true, "1.3.0": false},
+		">=1.0 <2.0 || 3.x":       {"1.5.0": true, "2.5.0": false, "3.1.0": true, "4.0.0": false},
+		">1.2":                    {"1.2.9": false, "1.3.0": true},
+		"<=1.2":                   {"1.2.9": true, "1.3.0": false},
+		"!=1.2":                   {"1.1.0": true, "1.2.5": false},
+		"1.2.3 - 2.3":             {"1.2.2": false, "1.2.3": true, "2.3.9": true, "2.4.0": false},
+		"*":                       {"0.0.1": true, "10.0.0": true, "1.0.0-rc.1": false},
+		">=1.2.3-rc.1 <1.3.0":     {"1.2.3-rc.0": false, "1.2.3-rc.2": true, "1.2.4-rc.1": false, "1.2.4": true},
+	} {
+		c, err := NewConstraint(constraint)
+		assert.Nil(err, constraint)
+		for version, expected := range cases {
+			sv, err := NewSemver(version)
+			assert.Nil(err)
+			assert.Equal(expected, c.Check(sv), constraint+" "+version)
+		}
+	}
+
+	for _, constraint := range []string{"", ">=1.2.0 ||", ">=foo", "1.2.3.4", "1.x.3", ">*", "1.2-rc.1",
+		`1.2.3-"x`, "1.2.3-rc..1", "1.2.3-01", "1.2.3-", "1.2.3+", "1.2.3+build_1"} {
+		_, err := NewConstraint(constraint)
+		assert.NotNil(err, constraint)
+	}
+}
+
+func TestConstraintValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := NewConstraint(">=1.0 <2.0 || 3.x")
+	assert.Nil(err)
+
+	sv, err := NewSemver("2.5.0")
+	assert.Nil(err)
+	err = c.Validate(sv)
+	assert.NotNil(err)
+	assert.Equal("2.5.0 does not satisfy `>=1.0 <2.0 || 3.x`: 2.5.0 is not <2.0; 2.5.0 is not 3.x", err.Error())
+
+	sv, err = NewSemver("1.5.0-rc.1")
+	assert.Nil(err)
+	err = c.Validate(sv)
+	assert.NotNil(err)
+	assert.Contains("1.5.0-rc.1 is a pre-release", err.Error())
+
+	sv, err = NewSemver("3.1.0")
+	assert.Nil(err)
+	assert.Nil(c.Validate(sv))
+
+	err = c.Validate(nil)
+	assert.NotNil(err)
+	assert.Equal("a nil version does not satisfy `>=1.0 <2.0 || 3.x`", err.Error())
+	assert.False(c.Check(nil))
+}
+
+func TestConstraintMaxSatisfying(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := NewConstraint("~1.2")
+	assert.Nil(err)
+
+	var versions Semvers
+	for _, version := range []string{"1.2.0", "1.3.0", "1.2.10", "1.2.9", "1.2.11-rc.1"} {
+		sv, err := NewSemver(version)
+		assert.Nil(err)
+		versions = append(versions, sv)
+	}
+	assert.Equal("1.2.10", c.MaxSatisfying(versions).String())
+	assert.Equal("1.2.10", c.MaxSatisfying(append(Semvers{nil}, versions...)).String())
+
+	c, err = NewConstraint("^2")
+	assert.Nil(err)
+	assert.Nil(c.MaxSatisfying(versions))
+}
+
+func TestConstraintMarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	type release struct {
+		Requires Constraint `json:"requires" yaml:"requires"`
+	}
+
+	var fromJSON release
+	assert.Nil(json.Unmarshal([]byte(`{"requires":"^1.2 || 2.x"}`), &fromJSON))
+	assert.Equal("^1.2 || 2.x", fromJSON.Requires.String())
+	data, err := json.Marshal(fromJSON)
+	assert.Nil(err)
+	assert.Equal(`{"requires":"^1.2 || 2.x"}`, string(data))
+
+	var fromYAML release
+	assert.Nil(yaml.Unmarshal([]byte("requires: ~1.4.0\n"), &fromYAML))
+	sv, err := NewSemver("1.4.2")
+	assert.Nil(err)
+	assert.True(fromYAML.Requires.Check(sv))
+	data, err = yaml.Marshal(fromYAML)
+	assert.Nil(err)
+	assert.Equal("requires: ~1.4.0\n", string(data))
+
+	assert.NotNil(yaml.Unmarshal([]byte("requires: '>=foo'\n"), &fromYAML))
+
+	c, err := NewConstraint(">=1.2.3-rc.1+build.5 <2")
+	assert.Nil(err)
+	data, err = json.Marshal(c)
+	assert.Nil(err)
+	var fromString string
+	assert.Nil(json.Unmarshal(data, &fromString))
+	assert.Equal(">=1.2.3-rc.1+build.5 <2", fromString)
+	var roundTrip Constraint
+	assert.Nil(json.Unmarshal(data, &roundTrip))
+	assert.Equal(c.String(), roundTrip.String())
+
+	assert.NotNil(json.Unmarshal([]byte(`{"requires":3}`), &fromJSON))
+}
@@ -0,0 +1,244 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces sensitive values in error messages and dumps.
+const Redacted = "***"
+
+// DefaultSensitivePattern matches var names that typically hold credentials.
+var DefaultSensitivePattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|api_?key|private_?key)`)
+
+// Sensitive wraps a var value that should only ever appear in rendered output.
+// `.Var` returns the wrapped value, while printing a `Sensitive` with fmt or marshalling it to json or yaml gives `***`.
+type Sensitive struct {
+	Value interface{}
+}
+
+// String implements fmt.Stringer.
+func (s Sensitive) String() string {
+	return Redacted
+}
+
+// GoString implements fmt.GoStringer.
+func (s Sensitive) GoString() string {
+	return Redacted
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Sensitive) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Redacted)
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (s Sensitive) MarshalYAML() (interface{}, error) {
+	return Redacted, nil
+}
+
+// MarkSensitive returns a copy of vars with every value wrapped in `Sensitive`, e.g. for vars read from a secret source.
+func MarkSensitive(vars Vars) Vars {
+	marked, _ := markSensitive(normalizeValue(vars), nil, nil, true).(map[string]interface{})
+	if marked == nil {
+		marked = Vars{}
+	}
+	return marked
+}
+
+// WithSensitivePattern marks every var whose name matches the pattern, and everything nested beneath it, as sensitive.
+// Sensitive values render normally but are replaced with `***` in errors returned by `Process` and in `Vars`.
+func (t *Template) WithSensitivePattern(pattern *regexp.Regexp) *Template {
+	t.sensitivePattern = pattern
+	return t
+}
+
+// Vars returns the template's vars with any schema defaults applied and sensitive values wrapped in `Sensitive`,
+// so they can be dumped safely.
+func (t *Template) Vars() Vars {
+	vars := t.vars
+	if t.schema != nil {
+		vars = t.schema.ApplyDefaults(vars)
+	}
+	return t.markSensitive(vars)
+}
+
+// markSensitive wraps the values marked sensitive by name or by the schema.
+func (t *Template) markSensitive(vars Vars) Vars {
+	if t.sensitivePattern == nil && t.schema == nil {
+		return vars
+	}
+	marked, _ := markSensitive(normalizeValue(vars), t.schema, t.sensitivePattern, false).(map[string]interface{})
+	if marked == nil {
+		marked = Vars{}
+	}
+	return marked
+}
+
+// markSensitive wraps every value beneath a sensitive key or schema; maps and lists are never wrapped themselves
+// so paths into them still resolve. The value must be normalized, and is modified in place.
+func markSensitive(value interface{}, schema *Schema, pattern *regexp.Regexp, sensitive bool) interface{} {
+	if schema != nil && schema.Sensitive {
+		sensitive = true
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range typed {
+			var property *Schema
+			if schema != nil {
+				property = schema.Properties[key]
+			}
+			typed[key] = markSensitive(elem, property, pattern, sensitive || (pattern != nil && pattern.MatchString(key)))
+		}
+	case []interface{}:
+		var items *Schema
+		if schema != nil {
+			items = schema.Items
+		}
+		for index, elem := range typed {
+			typed[index] = markSensitive(elem, items, pattern, sensitive)
+		}
+	case Sensitive:
+	default:
+		if sensitive {
+			return Sensitive{Value: value}
+		}
+	}
+	return value
+}
+
+// unwrapSensitive returns a value with every `Sensitive` within it unwrapped, copying maps and lists as needed.
+func unwrapSensitive(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case Sensitive:
+		return unwrapSensitive(typed.Value)
+	case map[string]interface{}:
+		unwrapped := make(map[string]interface{}, len(typed))
+		for key, elem := range typed {
+			unwrapped[key] = unwrapSensitive(elem)
+		}
+		return unwrapped
+	case []interface{}:
+		unwrapped := make([]interface{}, len(typed))
+		for index, elem := range typed {
+			unwrapped[index] = unwrapSensitive(elem)
+		}
+		return unwrapped
+	default:
+		return value
+	}
+}
+
+// RedactedError is an error whose message had sensitive values replaced with `***`.
+// Err is the original error, and should not be logged.
+type RedactedError struct {
+	Err     error
+	message string
+}
+
+// Error implements error.
+func (e *RedactedError) Error() string {
+	return e.message
+}
+
+// sensitiveValues returns the text of every sensitive var and every secret read during the last render, along
+// with its quoted form, longest first so a value containing another is redacted whole.
+func (t *Template) sensitiveValues() []string {
+	values := append([]string{}, t.secretValues...)
+	var collect func(value interface{}, sensitive bool)
+	collect = func(value interface{}, sensitive bool) {
+		switch typed := value.(type) {
+		case Sensitive:
+			collect(typed.Value, true)
+		case map[string]interface{}:
+			for _, elem := range typed {
+				collect(elem, sensitive)
+			}
+		case []interface{}:
+			for _, elem := range typed {
+				collect(elem, sensitive)
+			}
+		default:
+			if sensitive && isRedactable(typed) {
+				values = append(values, fmt.Sprintf("%v", typed))
+			}
+		}
+	}
+	collect(t.vars, false)
+
+	seen := map[string]bool{}
+	var redacted []string
+	for _, value := range values {
+		if len(value) == 0 {
+			continue
+		}
+		quoted := strconv.Quote(value)
+		for _, form := range []string{value, quoted, quoted[1 : len(quoted)-1]} {
+			if !seen[form] {
+				seen[form] = true
+				redacted = append(redacted, form)
+			}
+		}
+	}
+	sort.Slice(redacted, func(i, j int) bool { return len(redacted[i]) > len(redacted[j]) })
+	return redacted
+}
+
+// isRedactable returns if a sensitive value is redacted from error messages. Booleans and numbers are left alone,
+// as redacting them would hide every `true` or `0` in a message, and they can't be much of a secret anyway.
+func isRedactable(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return false
+	}
+	return true
+}
+
+// redactError replaces sensitive values in an error's message with `***`.
+// The error types `Process` documents are kept, with their wrapped errors redacted instead.
+func (t *Template) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	values := t.sensitiveValues()
+	if len(values) == 0 {
+		return err
+	}
+	switch typed := err.(type) {
+	case *MissingVarsError:
+		typed.Err = redactError(typed.Err, values)
+		return typed
+	case *ValidationError:
+		typed.Err = redactError(typed.Err, values)
+		return typed
+	case *SchemaError, *SandboxError:
+		return err
+	}
+	return redactError(err, values)
+}
+
+func redactError(err error, values []string) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	redacted := message
+	for _, value := range values {
+		redacted = strings.Replace(redacted, value, Redacted, -1)
+	}
+	if redacted == message {
+		return err
+	}
+	return &RedactedError{Err: err, message: redacted}
+}
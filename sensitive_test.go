@@ -0,0 +1,132 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestSensitive(t *testing.T) {
+	assert := assert.New(t)
+
+	value := Sensitive{Value: "hunter2"}
+	assert.Equal(Redacted, fmt.Sprintf("%v", value))
+	assert.Equal(Redacted, fmt.Sprintf("%#v", value))
+
+	contents, err := json.Marshal(Vars{"password": value})
+	assert.Nil(err)
+	assert.Equal(`{"password":"***"}`, string(contents))
+
+	contents, err = yaml.Marshal(Vars{"password": value})
+	assert.Nil(err)
+	assert.Equal("password: '***'\n", string(contents))
+}
+
+func TestTemplateSensitivePattern(t *testing.T) {
+	assert := assert.New(t)
+
+	temp := New().
+		WithSensitivePattern(DefaultSensitivePattern).
+		WithVar("name", "test-service").
+		WithVar("db", map[string]interface{}{"password": "hunter2", "host": "localhost"})
+
+	buffer := bytes.NewBuffer(nil)
+	err := temp.WithBody(`{{ .Var "name" }} {{ .Var "db.password" }} {{ .Var "db" }}`).Process(buffer)
+	assert.Nil(err)
+	assert.Equal("test-service hunter2 map[host:localhost password:hunter2]", buffer.String())
+
+	vars := temp.Vars()
+	assert.Equal("test-service", vars["name"])
+	assert.Equal(Sensitive{Value: "hunter2"}, vars["db"].(map[string]interface{})["password"])
+
+	err = temp.WithBody(`{{ .Var "db.password" | int }}`).Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	_, isRedacted := err.(*RedactedError)
+	assert.True(isRedacted)
+	assert.NotContains("hunter2", err.Error())
+	assert.Contains(Redacted, err.Error())
+}
+
+func TestTemplateSensitiveRedactsSubstrings(t *testing.T) {
+	assert := assert.New(t)
+
+	temp := New().
+		WithSensitivePattern(DefaultSensitivePattern).
+		WithVar("maxTokens", 5).
+		WithVar("secretEnabled", true).
+		WithVar("apiKey", `p"ss\w`).
+		WithVar("password", "e").
+		WithVar("token", "")
+
+	err := temp.WithBody(`{{ .Var "missing" "5 true xp\"ss\\wx" | int }}`).Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	_, isRedacted := err.(*RedactedError)
+	assert.True(isRedacted)
+	assert.Contains(`"5 tru*** x***x"`, err.Error())
+	assert.NotContains(`ss`, err.Error())
+
+	err = temp.WithBody(`{{ .Var "apiKey" | int }}`).Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	assert.Contains("parsing ***: invalid syntax", err.Error())
+}
+
+func TestTemplateSensitiveSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	schema, err := ParseSchema([]byte(`
+properties:
+  token:
+    type: string
+    pattern: "^[a-f0-9]+$"
+    sensitive: true
+  name:
+    type: string
+`))
+	assert.Nil(err)
+
+	err = New().
+		WithBody(`{{ .Var "token" }}`).
+		WithSchema(schema).
+		WithVar("token", "not-hex").
+		WithVar("name", "test-service").
+		Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	assert.NotContains("not-hex", err.Error())
+	assert.Contains("value `***` does not match pattern", err.Error())
+}
+
+func TestTemplateSecretRedacted(t *testing.T) {
+	assert := assert.New(t)
+
+	provider := secretProviderFunc(func(path string) (string, error) {
+		return "hunter2", nil
+	})
+	err := New().
+		WithBody(`{{ secret "db/password" | int }}`).
+		WithSecretProvider(provider).
+		Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	assert.NotContains("hunter2", err.Error())
+}
+
+func TestMarkSensitive(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := MarkSensitive(Vars{"db": map[interface{}]interface{}{"hosts": []interface{}{"a"}}})
+	assert.Equal(Sensitive{Value: "a"}, vars["db"].(map[string]interface{})["hosts"].([]interface{})[0])
+
+	buffer := bytes.NewBuffer(nil)
+	err := New().WithVars(vars).WithBody(`{{ .Var "db.hosts[0]" }}`).Process(buffer)
+	assert.Nil(err)
+	assert.Equal("a", buffer.String())
+}
+
+type secretProviderFunc func(path string) (string, error)
+
+func (f secretProviderFunc) Secret(path string) (string, error) {
+	return f(path)
+}
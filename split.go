@@ -0,0 +1,84 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultDocumentNamePattern is the default file name pattern for documents split out of yaml output.
+const DefaultDocumentNamePattern = "{{kind}}-{{metadata.name}}.yml"
+
+// documentNamePlaceholder matches the `{{path}}` placeholders in a document name pattern.
+var documentNamePlaceholder = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// documentNameSeparators replaces path separators in placeholder values, so a value like `platform/core` stays
+// in one file name.
+var documentNameSeparators = strings.NewReplacer("/", "-", `\`, "-")
+
+// SplitDocuments splits yaml output into its `---` separated documents.
+// Documents that are empty or only contain comments, such as those left by false `if` blocks, are dropped.
+func SplitDocuments(output []byte) []string {
+	var documents []string
+	var document []string
+	flush := func() {
+		if !isEmptyDocument(document) {
+			documents = append(documents, strings.Join(document, ""))
+		}
+		document = nil
+	}
+
+	for _, line := range strings.SplitAfter(string(output), "\n") {
+		if isYAMLDocumentSeparator(line) {
+			flush()
+			continue
+		}
+		document = append(document, line)
+	}
+	flush()
+	return documents
+}
+
+func isEmptyDocument(lines []string) bool {
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) > 0 && !strings.HasPrefix(trimmed, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// DocumentName renders a file name for a yaml document from a pattern such as `{{kind}}-{{metadata.name}}.yml`.
+// Placeholders are dotted paths into the document; `{{index}}` is the 1-based position of the document.
+// Names that are empty, `.`, `..` or contain a path separator are rejected, so every document stays in one directory.
+func DocumentName(pattern, document string, index int) (string, error) {
+	values := Vars{}
+	if err := yaml.Unmarshal([]byte(document), &values); err != nil {
+		return "", fmt.Errorf("document %d: %v", index, err)
+	}
+
+	var missing []string
+	name := documentNamePlaceholder.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		path := documentNamePlaceholder.FindStringSubmatch(placeholder)[1]
+		if path == "index" {
+			return strconv.Itoa(index)
+		}
+		value, hasValue := lookupVarPath(values, path)
+		if !hasValue || value == nil {
+			missing = append(missing, path)
+			return ""
+		}
+		return documentNameSeparators.Replace(fmt.Sprintf("%v", value))
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("document %d has no value for `%s` in the name pattern", index, strings.Join(missing, "`, `"))
+	}
+	if len(name) == 0 || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("document %d is named `%s`, which is not a file name", index, name)
+	}
+	return name, nil
+}
@@ -0,0 +1,60 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	assert := assert.New(t)
+
+	documents := SplitDocuments([]byte("---\nkind: Deployment\n---\n\n# nothing here\n---\nkind: Service\n---\n"))
+	assert.Equal([]string{"kind: Deployment\n", "kind: Service\n"}, documents)
+	assert.Empty(SplitDocuments([]byte("\n---\n")))
+}
+
+func TestSplitDocumentsTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	temp, err := NewFromFile("testdata/test.template.yml")
+	assert.Nil(err)
+	temp = temp.WithVar("name", "test-service").WithVar("accessibility", "external")
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(temp.Process(buffer))
+
+	documents := SplitDocuments(buffer.Bytes())
+	assert.Len(documents, 3)
+
+	var names []string
+	for index, document := range documents {
+		name, err := DocumentName(DefaultDocumentNamePattern, document, index+1)
+		assert.Nil(err)
+		names = append(names, name)
+	}
+	assert.Equal([]string{"Deployment-test-service.yml", "Service-test-service.yml", "Ingress-test-service.yml"}, names)
+}
+
+func TestDocumentName(t *testing.T) {
+	assert := assert.New(t)
+
+	name, err := DocumentName("{{ index }}_{{metadata.labels.team}}.yaml", "metadata:\n  labels:\n    team: platform/core\n", 2)
+	assert.Nil(err)
+	assert.Equal("2_platform-core.yaml", name)
+
+	_, err = DocumentName(DefaultDocumentNamePattern, "kind: ConfigMap\n", 1)
+	assert.NotNil(err)
+	_, err = DocumentName(DefaultDocumentNamePattern, "kind: [", 1)
+	assert.NotNil(err)
+
+	name, err = DocumentName("{{metadata.name}}.yml", "metadata:\n  name: 'a\\b'\n", 1)
+	assert.Nil(err)
+	assert.Equal("a-b.yml", name)
+
+	for _, pattern := range []string{"{{metadata.name}}", "{{metadata.namespace}}", "../{{metadata.name}}.yml", "out/{{index}}.yml"} {
+		_, err = DocumentName(pattern, "metadata:\n  name: ..\n  namespace: ''\n", 1)
+		assert.NotNil(err, pattern)
+	}
+}
@@ -0,0 +1,544 @@
+package template
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// WithSprig adds a set of functions compatible with Sprig, the function library used by Helm, so Helm style
+// templates render unchanged. Where a Sprig function shares a name with a built-in one, such as `indent`, `split`
+// or `int`, the Sprig behavior replaces the built-in one.
+func (t *Template) WithSprig() *Template {
+	for name, fn := range t.sprigFuncMap() {
+		t.funcs[name] = fn
+	}
+	return t
+}
+
+func (t *Template) sprigFuncMap() texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		// defaults and flow control
+		"default": func(defaultValue interface{}, given ...interface{}) interface{} {
+			if len(given) == 0 || isEmptyValue(given[0]) {
+				return defaultValue
+			}
+			return given[0]
+		},
+		"empty": isEmptyValue,
+		"coalesce": func(values ...interface{}) interface{} {
+			for _, value := range values {
+				if !isEmptyValue(value) {
+					return value
+				}
+			}
+			return nil
+		},
+		"ternary": func(trueValue, falseValue interface{}, condition bool) interface{} {
+			if condition {
+				return trueValue
+			}
+			return falseValue
+		},
+		"required": func(message string, value interface{}) (interface{}, error) {
+			if value == nil {
+				return nil, errors.New(message)
+			}
+			if str, isString := value.(string); isString && len(str) == 0 {
+				return nil, errors.New(message)
+			}
+			return value, nil
+		},
+		"fail": func(message string) (string, error) {
+			return "", errors.New(message)
+		},
+
+		// strings
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"title": strings.Title,
+		"trim":  strings.TrimSpace,
+		"trimAll": func(cutset, v string) string {
+			return strings.Trim(v, cutset)
+		},
+		"trimPrefix": func(prefix, v string) string {
+			return strings.TrimPrefix(v, prefix)
+		},
+		"trimSuffix": func(suffix, v string) string {
+			return strings.TrimSuffix(v, suffix)
+		},
+		"hasPrefix": func(prefix, v string) bool {
+			return strings.HasPrefix(v, prefix)
+		},
+		"hasSuffix": func(suffix, v string) bool {
+			return strings.HasSuffix(v, suffix)
+		},
+		"contains": func(substr, v string) bool {
+			return strings.Contains(v, substr)
+		},
+		"replace": func(old, new, v string) string {
+			return strings.Replace(v, old, new, -1)
+		},
+		"repeat": func(count int, v string) string {
+			return strings.Repeat(v, count)
+		},
+		"trunc": func(length int, v string) string {
+			if length < 0 && len(v)+length > 0 {
+				return v[len(v)+length:]
+			}
+			if length >= 0 && len(v) > length {
+				return v[:length]
+			}
+			return v
+		},
+		"nospace": func(v string) string {
+			return strings.Map(func(r rune) rune {
+				if unicode.IsSpace(r) {
+					return -1
+				}
+				return r
+			}, v)
+		},
+		"quote": func(values ...interface{}) string {
+			quoted := make([]string, 0, len(values))
+			for _, value := range values {
+				if value != nil {
+					quoted = append(quoted, strconv.Quote(sprigString(value)))
+				}
+			}
+			return strings.Join(quoted, " ")
+		},
+		"squote": func(values ...interface{}) string {
+			quoted := make([]string, 0, len(values))
+			for _, value := range values {
+				if value != nil {
+					quoted = append(quoted, "'"+sprigString(value)+"'")
+				}
+			}
+			return strings.Join(quoted, " ")
+		},
+		"indent": func(spaces int, v string) string {
+			pad := strings.Repeat(" ", spaces)
+			return pad + strings.Replace(v, "\n", "\n"+pad, -1)
+		},
+		"nindent": func(spaces int, v string) string {
+			pad := strings.Repeat(" ", spaces)
+			return "\n" + pad + strings.Replace(v, "\n", "\n"+pad, -1)
+		},
+		"split": func(sep, v string) map[string]string {
+			parts := strings.Split(v, sep)
+			result := make(map[string]string, len(parts))
+			for index, part := range parts {
+				result["_"+strconv.Itoa(index)] = part
+			}
+			return result
+		},
+		"splitList": func(sep, v string) []string {
+			return strings.Split(v, sep)
+		},
+		"join": func(sep string, v interface{}) string {
+			return strings.Join(sprigStrings(v), sep)
+		},
+		"regexMatch": func(expr, v string) (bool, error) {
+			return regexp.MatchString(expr, v)
+		},
+		"regexReplaceAll": func(expr, v, replacement string) (string, error) {
+			compiled, err := regexp.Compile(expr)
+			if err != nil {
+				return "", err
+			}
+			return compiled.ReplaceAllString(v, replacement), nil
+		},
+
+		// conversions
+		"toString":  sprigString,
+		"toStrings": sprigStrings,
+		"atoi": func(v string) int {
+			value, _ := strconv.Atoi(v)
+			return value
+		},
+		"int": func(v interface{}) int {
+			return int(sprigInt64(v))
+		},
+		"int64":   sprigInt64,
+		"float64": sprigFloat64,
+		"toYaml": func(v interface{}) (string, error) {
+			data, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(data), "\n"), err
+		},
+		"fromYaml": func(v string) (map[string]interface{}, error) {
+			values := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(v), &values); err != nil {
+				return nil, err
+			}
+			return normalizeValue(values).(map[string]interface{}), nil
+		},
+		"toJson": func(v interface{}) (string, error) {
+			data, err := json.Marshal(normalizeValue(v))
+			return string(data), err
+		},
+		"toPrettyJson": func(v interface{}) (string, error) {
+			data, err := json.MarshalIndent(normalizeValue(v), "", "  ")
+			return string(data), err
+		},
+		"fromJson": func(v string) (map[string]interface{}, error) {
+			values := map[string]interface{}{}
+			err := json.Unmarshal([]byte(v), &values)
+			return values, err
+		},
+		"b64enc": func(v string) string {
+			return base64.StdEncoding.EncodeToString([]byte(v))
+		},
+		"b64dec": func(v string) (string, error) {
+			data, err := base64.StdEncoding.DecodeString(v)
+			return string(data), err
+		},
+		"sha1sum": func(v string) string {
+			sum := sha1.Sum([]byte(v))
+			return hex.EncodeToString(sum[:])
+		},
+		"sha256sum": func(v string) string {
+			sum := sha256.Sum256([]byte(v))
+			return hex.EncodeToString(sum[:])
+		},
+
+		// lists and dicts
+		"list": func(values ...interface{}) []interface{} {
+			return values
+		},
+		"dict": func(pairs ...interface{}) map[string]interface{} {
+			dict := make(map[string]interface{}, len(pairs)/2)
+			for index := 0; index < len(pairs); index += 2 {
+				key := sprigString(pairs[index])
+				if index+1 < len(pairs) {
+					dict[key] = pairs[index+1]
+				} else {
+					dict[key] = ""
+				}
+			}
+			return dict
+		},
+		"hasKey": func(dict interface{}, key string) bool {
+			_, hasKey := sprigMapIndex(dict, key)
+			return hasKey
+		},
+		"get": func(dict interface{}, key string) interface{} {
+			value, hasKey := sprigMapIndex(dict, key)
+			if !hasKey {
+				return ""
+			}
+			return value
+		},
+		"set": func(dict map[string]interface{}, key string, value interface{}) map[string]interface{} {
+			dict[key] = value
+			return dict
+		},
+		"unset": func(dict map[string]interface{}, key string) map[string]interface{} {
+			delete(dict, key)
+			return dict
+		},
+		"merge": func(dict map[string]interface{}, sources ...interface{}) (map[string]interface{}, error) {
+			for _, source := range sources {
+				values, err := toVars(source)
+				if err != nil {
+					return nil, err
+				}
+				sprigMerge(dict, values)
+			}
+			return dict, nil
+		},
+		"pluck": func(key string, dicts ...interface{}) []interface{} {
+			var values []interface{}
+			for _, dict := range dicts {
+				if value, hasKey := sprigMapIndex(dict, key); hasKey {
+					values = append(values, value)
+				}
+			}
+			return values
+		},
+		"keys": func(dicts ...interface{}) []string {
+			var keys []string
+			for _, dict := range dicts {
+				value := reflect.ValueOf(dict)
+				if value.Kind() != reflect.Map {
+					continue
+				}
+				for _, key := range value.MapKeys() {
+					keys = append(keys, fmt.Sprintf("%v", key.Interface()))
+				}
+			}
+			sort.Strings(keys)
+			return keys
+		},
+		"first": func(list interface{}) interface{} {
+			value := reflect.ValueOf(list)
+			if (value.Kind() != reflect.Slice && value.Kind() != reflect.Array) || value.Len() == 0 {
+				return nil
+			}
+			return value.Index(0).Interface()
+		},
+		"last": func(list interface{}) interface{} {
+			value := reflect.ValueOf(list)
+			if (value.Kind() != reflect.Slice && value.Kind() != reflect.Array) || value.Len() == 0 {
+				return nil
+			}
+			return value.Index(value.Len() - 1).Interface()
+		},
+		"append": func(list interface{}, value interface{}) ([]interface{}, error) {
+			return appendValue(value, list)
+		},
+		"prepend": func(list interface{}, value interface{}) ([]interface{}, error) {
+			return prependValue(value, list)
+		},
+		"without": func(list interface{}, values ...interface{}) ([]interface{}, error) {
+			return without(append(values, list)...)
+		},
+		"uniq":    uniq,
+		"compact": compact,
+		"until": func(count int) []int {
+			values := make([]int, 0, count)
+			for index := 0; index < count; index++ {
+				values = append(values, index)
+			}
+			return values
+		},
+
+		// math
+		"add": func(values ...interface{}) int64 {
+			var sum int64
+			for _, value := range values {
+				sum += sprigInt64(value)
+			}
+			return sum
+		},
+		"add1": func(v interface{}) int64 {
+			return sprigInt64(v) + 1
+		},
+		"sub": func(a, b interface{}) int64 {
+			return sprigInt64(a) - sprigInt64(b)
+		},
+		"mul": func(a interface{}, values ...interface{}) int64 {
+			product := sprigInt64(a)
+			for _, value := range values {
+				product *= sprigInt64(value)
+			}
+			return product
+		},
+		"div": func(a, b interface{}) (int64, error) {
+			if sprigInt64(b) == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return sprigInt64(a) / sprigInt64(b), nil
+		},
+		"mod": func(a, b interface{}) (int64, error) {
+			if sprigInt64(b) == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return sprigInt64(a) % sprigInt64(b), nil
+		},
+		"max": func(a interface{}, values ...interface{}) int64 {
+			result := sprigInt64(a)
+			for _, value := range values {
+				if v := sprigInt64(value); v > result {
+					result = v
+				}
+			}
+			return result
+		},
+		"min": func(a interface{}, values ...interface{}) int64 {
+			result := sprigInt64(a)
+			for _, value := range values {
+				if v := sprigInt64(value); v < result {
+					result = v
+				}
+			}
+			return result
+		},
+
+		// dates
+		"now": time.Now,
+		"date": func(format string, date interface{}) string {
+			return sprigTime(date).Format(format)
+		},
+
+		// environment and misc
+		"env": func(key string) (string, error) {
+			value, _, err := t.lookupEnv(key)
+			return value, err
+		},
+		"uuidv4": func() string {
+			return UUIDv4().String()
+		},
+		"typeOf": func(v interface{}) string {
+			return fmt.Sprintf("%T", v)
+		},
+		"kindOf": func(v interface{}) string {
+			if v == nil {
+				return "invalid"
+			}
+			return reflect.ValueOf(v).Kind().String()
+		},
+	}
+}
+
+// sprigMerge deep merges source into dict the way Sprig's `merge` does: keys dict already has are kept,
+// and dict is modified in place.
+func sprigMerge(dict map[string]interface{}, source map[string]interface{}) {
+	for key, value := range source {
+		existing, hasKey := dict[key]
+		if !hasKey {
+			dict[key] = value
+			continue
+		}
+		existingMap, isMap := existing.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		if values, err := toVars(value); err == nil {
+			sprigMerge(existingMap, values)
+		}
+	}
+}
+
+// isEmptyValue returns if a value is empty the way Sprig's `empty` defines it: nil, false, zero, or of zero length.
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+func sprigString(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case []byte:
+		return string(typed)
+	case error:
+		return typed.Error()
+	case fmt.Stringer:
+		return typed.String()
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// sprigStrings converts a list to strings, skipping nil elements; any other value becomes a single string.
+func sprigStrings(value interface{}) []string {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		if value == nil {
+			return []string{}
+		}
+		return []string{sprigString(value)}
+	}
+	values := make([]string, 0, v.Len())
+	for index := 0; index < v.Len(); index++ {
+		if elem := v.Index(index).Interface(); elem != nil {
+			values = append(values, sprigString(elem))
+		}
+	}
+	return values
+}
+
+// sprigInt64 converts a number, bool or numeric string to an int64, or 0 if it isn't one.
+func sprigInt64(value interface{}) int64 {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return int64(v.Float())
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.String:
+		if parsed, err := strconv.ParseInt(v.String(), 0, 64); err == nil {
+			return parsed
+		}
+		if parsed, err := strconv.ParseFloat(v.String(), 64); err == nil && !math.IsNaN(parsed) {
+			return int64(parsed)
+		}
+	}
+	return 0
+}
+
+// sprigFloat64 converts a number, bool or numeric string to a float64, or 0 if it isn't one.
+func sprigFloat64(value interface{}) float64 {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		parsed, _ := strconv.ParseFloat(v.String(), 64)
+		return parsed
+	}
+	return float64(sprigInt64(value))
+}
+
+// sprigTime converts a time or unix timestamp to a time in the local zone.
+func sprigTime(date interface{}) time.Time {
+	switch typed := date.(type) {
+	case time.Time:
+		return typed
+	case *time.Time:
+		return *typed
+	case int64:
+		return time.Unix(typed, 0)
+	case int:
+		return time.Unix(int64(typed), 0)
+	case int32:
+		return time.Unix(int64(typed), 0)
+	}
+	return time.Now()
+}
+
+// sprigMapIndex returns a value from any map with string-like keys, including the map[interface{}]interface{}
+// maps yaml produces.
+func sprigMapIndex(dict interface{}, key string) (interface{}, bool) {
+	v := reflect.ValueOf(dict)
+	if v.Kind() != reflect.Map {
+		return nil, false
+	}
+	for _, mapKey := range v.MapKeys() {
+		if fmt.Sprintf("%v", mapKey.Interface()) == key {
+			return v.MapIndex(mapKey).Interface(), true
+		}
+	}
+	return nil, false
+}
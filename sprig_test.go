@@ -0,0 +1,89 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestTemplateSprig(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `metadata:
+  name: {{ .Var "name" | trunc 12 | quote }}
+  labels:
+    {{- dict "app" (.Var "name") "tier" (.Var "tier" "" | default "web") | toYaml | nindent 4 }}
+spec:
+  replicas: {{ .Var "replicas" | int | add 1 }}
+  hosts: {{ list "a" "b" (coalesce (.Var "extra" "") "c") | join "," }}
+  public: {{ ternary "yes" "no" (eq (.Var "accessibility") "external") }}
+  env: {{ hasKey (.Var "env") "DATABASE_URL" | not }}
+`
+	expected := `metadata:
+  name: "test-service"
+  labels:
+    app: test-service-long
+    tier: web
+spec:
+  replicas: 3
+  hosts: a,b,c
+  public: yes
+  env: true
+`
+
+	buffer := bytes.NewBuffer(nil)
+	err := New().
+		WithSprig().
+		WithBody(test).
+		WithVar("name", "test-service-long").
+		WithVar("replicas", "2").
+		WithVar("accessibility", "external").
+		WithVar("env", map[interface{}]interface{}{"PROVIDER_HOST": "https://test.provider.com"}).
+		Process(buffer)
+	assert.Nil(err)
+	assert.Equal(expected, buffer.String())
+}
+
+func TestTemplateSprigRequired(t *testing.T) {
+	assert := assert.New(t)
+
+	err := New().
+		WithSprig().
+		WithBody(`{{ .Var "name" "" | required "name is required" }}`).
+		Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+	assert.Contains("name is required", err.Error())
+}
+
+func TestIsEmptyValue(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isEmptyValue(nil))
+	assert.True(isEmptyValue(""))
+	assert.True(isEmptyValue(0))
+	assert.True(isEmptyValue(false))
+	assert.True(isEmptyValue([]interface{}{}))
+	assert.True(isEmptyValue(map[string]interface{}{}))
+	assert.False(isEmptyValue("foo"))
+	assert.False(isEmptyValue(1))
+	assert.False(isEmptyValue(struct{}{}))
+}
+
+func TestTemplateSprigCollections(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{{ $list := list "a" "b" "" "b" -}}
+{{ append $list "c" }} {{ prepend $list "z" }} {{ without $list "a" "" }} {{ uniq $list }} {{ compact $list }}
+{{ $a := dict "name" "a" "labels" (dict "app" "a") -}}
+{{ $b := dict "name" "b" "port" 80 "labels" (dict "app" "b" "tier" "web") -}}
+{{ $_ := merge $a $b -}}
+{{ $a.name }} {{ $a.port }} {{ $a.labels.app }} {{ $a.labels.tier }} {{ pluck "port" $a $b (dict) }}`
+	expected := `[a b  b c] [z a b  b] [b b] [a b ] [a b b]
+a 80 a web [80 80]`
+
+	buffer := bytes.NewBuffer(nil)
+	err := New().WithSprig().WithBody(test).Process(buffer)
+	assert.Nil(err)
+	assert.Equal(expected, buffer.String())
+}
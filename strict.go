@@ -0,0 +1,60 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	texttemplate "text/template"
+)
+
+// MissingVarsError is returned by `Process` in strict mode and lists every missing variable and env variable.
+type MissingVarsError struct {
+	Missing []Reference
+	// Err is the error that stopped rendering early, if any, e.g. a pipeline function rejecting the
+	// empty value substituted for a missing variable.
+	Err error
+}
+
+// Error implements error.
+func (e *MissingVarsError) Error() string {
+	buffer := bytes.NewBuffer(nil)
+	fmt.Fprintf(buffer, "template has %d missing variable(s):", len(e.Missing))
+	for _, missing := range e.Missing {
+		fmt.Fprintf(buffer, "\n\t%s: %s `%s` is unset and no default is provided", missing.Location(), missing.Kind, missing.Key)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(buffer, "\nrendering stopped early: %v", e.Err)
+	}
+	return buffer.String()
+}
+
+// recordMissing notes a missing variable during a strict render.
+func (t *Template) recordMissing(kind ReferenceKind, key string) {
+	for _, missing := range t.missing {
+		if missing.Kind == kind && missing.Key == key {
+			return
+		}
+	}
+	t.missing = append(t.missing, Reference{Kind: kind, Key: key})
+}
+
+// missingVarsError maps the missing variables recorded during a strict render back to where
+// the template references them.
+func (t *Template) missingVarsError(final *texttemplate.Template, err error) error {
+	references := findReferences(final.Templates())
+
+	missingErr := &MissingVarsError{Err: err}
+	for _, missing := range t.missing {
+		var found bool
+		for _, reference := range references {
+			if reference.Kind == missing.Kind && reference.Key == missing.Key && reference.IsRequired() {
+				missingErr.Missing = append(missingErr.Missing, reference)
+				found = true
+			}
+		}
+		if !found {
+			missing.Template = t.Name()
+			missingErr.Missing = append(missingErr.Missing, missing)
+		}
+	}
+	return missingErr
+}
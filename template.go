@@ -1,6 +1,7 @@
 package template
 
 import (
+	"bytes"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
@@ -16,7 +17,6 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"net/url"
-	"reflect"
 	"regexp"
 	"strconv"
 	texttemplate "text/template"
@@ -25,6 +25,9 @@ import (
 // Vars is a loose type alias to map[string]interface{}
 type Vars = map[string]interface{}
 
+// FuncMap is a loose type alias to text/template.FuncMap
+type FuncMap = texttemplate.FuncMap
+
 // New creates a new template.
 func New() *Template {
 	temp := &Template{
@@ -56,9 +59,30 @@ type Template struct {
 	body     string
 	vars     Vars
 	env      map[string]string
-	includes []string
+	includes []include
 	funcs    texttemplate.FuncMap
 	helpers  Helpers
+	strict   bool
+	missing  []Reference
+	schema   *Schema
+	files    []string
+	validate Validator
+	sandbox  *Sandbox
+	secrets  []SecretProvider
+
+	sensitivePattern *regexp.Regexp
+	// secretValues are the values returned by `secret` during the last render, for redaction.
+	secretValues []string
+
+	// executing is the parsed template set during `Process`, used by the `include` function.
+	executing    *texttemplate.Template
+	includeDepth int
+}
+
+// include is a (sub) template included into the rendering assets.
+type include struct {
+	name string
+	body string
 }
 
 // WithName sets the template name.
@@ -82,8 +106,45 @@ func (t *Template) WithBody(body string) *Template {
 }
 
 // WithInclude includes a (sub) template into the rendering assets.
+// Its `{{ define }}` blocks can be executed from the main template.
 func (t *Template) WithInclude(body string) *Template {
-	t.includes = append(t.includes, body)
+	t.includes = append(t.includes, include{body: body})
+	return t
+}
+
+// WithNamedInclude includes a (sub) template under a name, typically its file path, so the include
+// itself can be executed with `{{ template "<name>" . }}` in addition to any `{{ define }}` blocks it has.
+func (t *Template) WithNamedInclude(name, body string) *Template {
+	t.includes = append(t.includes, include{name: name, body: body})
+	return t
+}
+
+// WithStrict enables or disables strict mode. In strict mode a missing `.Var` or `.Env` renders as an empty string
+// instead of stopping the render, and `Process` returns a `*MissingVarsError` listing every missing reference at once.
+func (t *Template) WithStrict(strict bool) *Template {
+	t.strict = strict
+	return t
+}
+
+// WithSchema sets a schema the vars are validated against before the template is processed.
+// Defaults from the schema are set for any missing vars.
+func (t *Template) WithSchema(schema *Schema) *Template {
+	t.schema = schema
+	return t
+}
+
+// WithValidator sets a validator the rendered output is checked with before it is written, e.g. `ValidateYAML`.
+// If the output is invalid, `Process` writes nothing and returns a `*ValidationError` pointing at the template
+// line that rendered the offending output.
+func (t *Template) WithValidator(validator Validator) *Template {
+	t.validate = validator
+	return t
+}
+
+// WithSandbox restricts the files and env variables the template can read; `nil` lifts the restriction.
+// Reading a file outside the sandbox's roots or an env variable outside its allow list returns a `*SandboxError`.
+func (t *Template) WithSandbox(sandbox *Sandbox) *Template {
+	t.sandbox = sandbox
 	return t
 }
 
@@ -106,33 +167,67 @@ func (t *Template) WithVars(vars Vars) *Template {
 	return t
 }
 
+// WithMergedVars deep merges a map of variables over the template's existing variables.
+func (t *Template) WithMergedVars(vars Vars, lists ListMergeStrategy) *Template {
+	t.vars = MergeVars(t.vars, vars, lists)
+	return t
+}
+
 // SetVar sets a var in the template.
 func (t *Template) SetVar(key string, value interface{}) {
 	t.vars[key] = value
 }
 
+// SetVarPath sets a var at a dotted path (e.g. `db.hosts[0].name`), creating intermediate maps and lists as needed.
+func (t *Template) SetVarPath(path string, value interface{}) error {
+	vars, err := setVarPath(t.vars, path, value)
+	if err != nil {
+		return err
+	}
+	t.vars = vars
+	return nil
+}
+
 // HasVar returns if a variable is set.
+// The key can be a dotted path (e.g. `db.host` or `hosts[0]`) into nested maps and lists.
 func (t *Template) HasVar(key string) bool {
-	_, hasKey := t.vars[key]
+	_, hasKey := t.lookupVar(key)
 	return hasKey
 }
 
-// Var returns the value of a variable, or panics if the variable is not set.
+// Var returns the value of a variable, or an error if the variable is not set and no default is provided.
+// The key can be a dotted path (e.g. `db.host` or `hosts[0]`) into nested maps and lists.
 func (t *Template) Var(key string, defaults ...interface{}) (interface{}, error) {
-	if value, hasVar := t.vars[key]; hasVar {
-		return value, nil
+	if value, hasVar := t.lookupVar(key); hasVar {
+		return unwrapSensitive(value), nil
 	}
 
 	if len(defaults) > 0 {
 		return defaults[0], nil
 	}
 
+	if t.strict {
+		t.recordMissing(ReferenceVar, key)
+		return "", nil
+	}
 	return nil, fmt.Errorf("template variable `%s` is unset and no default is provided", key)
 }
 
+// lookupVar returns a variable by its exact key, falling back to resolving the key as a dotted path.
+func (t *Template) lookupVar(key string) (interface{}, bool) {
+	if value, hasVar := t.vars[key]; hasVar {
+		return value, true
+	}
+	return lookupVarPath(t.vars, key)
+}
+
 // Env returns an environment variable.
 func (t *Template) Env(key string, defaults ...string) (string, error) {
-	if value, hasVar := t.env[key]; hasVar {
+	value, hasVar, err := t.lookupEnv(key)
+	if err != nil {
+		return "", err
+	}
+	if hasVar {
 		return value, nil
 	}
 
@@ -140,58 +235,195 @@ func (t *Template) Env(key string, defaults ...string) (string, error) {
 		return defaults[0], nil
 	}
 
+	if t.strict {
+		t.recordMissing(ReferenceEnv, key)
+		return "", nil
+	}
 	return "", fmt.Errorf("template env variable `%s` is unset and no default is provided", key)
 }
 
 // HasEnv returns if an env var is set.
-func (t *Template) HasEnv(key string) bool {
-	_, hasKey := t.env[key]
-	return hasKey
+func (t *Template) HasEnv(key string) (bool, error) {
+	_, hasKey, err := t.lookupEnv(key)
+	return hasKey, err
+}
+
+// lookupEnv returns an env var, or a sandbox error if the sandbox doesn't allow it.
+func (t *Template) lookupEnv(key string) (string, bool, error) {
+	if t.sandbox != nil && !t.sandbox.AllowsEnv(key) {
+		return "", false, &SandboxError{Kind: ReferenceEnv, Key: key}
+	}
+	value, hasKey := t.env[key]
+	return value, hasKey, nil
 }
 
 // File returns the contents of a file.
 func (t *Template) File(path string) (string, error) {
+	t.files = append(t.files, path)
+	if t.sandbox != nil {
+		if err := t.sandbox.CheckFile(path); err != nil {
+			return "", err
+		}
+	}
 	contents, err := ioutil.ReadFile(path)
 	return string(contents), err
 }
 
-// HasFile returns if a file exists.
+// HasFile returns if a file exists. Files outside the sandbox, if one is set, never exist.
 func (t *Template) HasFile(path string) bool {
+	t.files = append(t.files, path)
+	if t.sandbox != nil && t.sandbox.CheckFile(path) != nil {
+		return false
+	}
 	_, err := os.Stat(path)
 	return err == nil
 }
 
+// Files returns the paths accessed through `.File` and `.HasFile` during the last call to `Process`.
+func (t *Template) Files() []string {
+	return t.files
+}
+
 // Helpers returns the helpers object.
 func (t *Template) Helpers() *Helpers {
 	return &t.helpers
 }
 
 // Process processes the template.
+// Sensitive values are replaced with `***` in the error returned, which is a `*RedactedError` if the message changed.
 func (t *Template) Process(dst io.Writer) error {
-	base := texttemplate.New(t.Name()).Funcs(t.ViewFuncs())
+	t.secretValues = nil
+	for _, provider := range t.secrets {
+		if cache, isCache := provider.(SecretCache); isCache {
+			cache.ClearCache()
+		}
+	}
+	return t.redactError(t.process(dst))
+}
 
-	var err error
-	for _, include := range t.includes {
-		_, err = base.New(t.Name()).Parse(include)
-		if err != nil {
+func (t *Template) process(dst io.Writer) error {
+	if t.schema != nil {
+		t.vars = t.schema.ApplyDefaults(t.vars)
+	}
+	t.vars = t.markSensitive(t.vars)
+	if t.schema != nil {
+		if err := t.schema.Validate(t.vars); err != nil {
 			return err
 		}
 	}
 
-	final, err := base.New(t.Name()).Parse(t.body)
+	final, err := t.parse()
+	if err != nil {
+		return err
+	}
+
+	t.missing = nil
+	t.files = nil
+	if t.validate == nil {
+		err = t.execute(final, dst)
+		if len(t.missing) > 0 {
+			return t.missingVarsError(final, err)
+		}
+		return err
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	tracker, err := t.trackOutput(final, buffer)
+	if err != nil {
+		return err
+	}
+	err = t.execute(final, buffer)
+	if len(t.missing) > 0 {
+		return t.missingVarsError(final, err)
+	}
 	if err != nil {
 		return err
 	}
+	if err = t.validate(buffer.Bytes()); err != nil {
+		return tracker.validationError(err)
+	}
+	_, err = buffer.WriteTo(dst)
+	return err
+}
+
+func (t *Template) execute(final *texttemplate.Template, dst io.Writer) error {
+	t.executing = final
+	defer func() { t.executing = nil }()
 	return final.Execute(dst, t)
 }
 
+// References parses the template and its includes and returns every variable, env variable and file
+// they reference with a literal key, without rendering anything.
+func (t *Template) References() ([]Reference, error) {
+	final, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+	return findReferences(final.Templates()), nil
+}
+
+// parse parses the includes and the body into a template set, returning the template for the body.
+func (t *Template) parse() (*texttemplate.Template, error) {
+	base := texttemplate.New(t.Name()).Funcs(t.ViewFuncs())
+
+	var err error
+	for _, include := range t.includes {
+		name := include.name
+		if len(name) == 0 {
+			name = t.Name()
+		}
+		_, err = base.New(name).Parse(include.body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return base.New(t.Name()).Parse(t.body)
+}
+
+// WithFuncs adds functions to the template, replacing any built-in functions with the same names.
+// As with text/template, it panics if a name is not a valid identifier or a value is not a function
+// returning either a single value, or a value and an error.
+func (t *Template) WithFuncs(funcs FuncMap) *Template {
+	texttemplate.New(t.Name()).Funcs(funcs)
+	for name, fn := range funcs {
+		t.funcs[name] = fn
+	}
+	return t
+}
+
 // ViewFuncs returns the view funcs.
 func (t *Template) ViewFuncs() texttemplate.FuncMap {
 	return t.funcs
 }
 
+// maxIncludeDepth limits how deeply `include` calls can nest, to stop a template including itself forever.
+const maxIncludeDepth = 256
+
+// include executes a named template with the given data and returns its output as a string.
+func (t *Template) include(name string, data interface{}) (string, error) {
+	if t.executing == nil {
+		return "", fmt.Errorf("include `%s`: template is not executing", name)
+	}
+	if t.includeDepth >= maxIncludeDepth {
+		return "", fmt.Errorf("include `%s`: exceeded maximum include depth of %d", name, maxIncludeDepth)
+	}
+
+	t.includeDepth++
+	defer func() { t.includeDepth-- }()
+
+	buffer := bytes.NewBuffer(nil)
+	if err := t.executing.ExecuteTemplate(buffer, name, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
 func (t *Template) baseFuncMap() texttemplate.FuncMap {
 	return texttemplate.FuncMap{
+		"include": t.include,
+		"secret":  t.secret,
+
 		"string": func(v interface{}) string {
 			return fmt.Sprintf("%v", v)
 		},
@@ -318,59 +550,31 @@ func (t *Template) baseFuncMap() texttemplate.FuncMap {
 			return strings.Split(v, sep)
 		},
 
-		"slice": func(from, to int, collection interface{}) (interface{}, error) {
-			value := reflect.ValueOf(collection)
-
-			if value.Type().Kind() != reflect.Slice {
-				return nil, fmt.Errorf("input must be a slice")
-			}
-
-			return value.Slice(from, to).Interface(), nil
-		},
-		"first": func(collection interface{}) (interface{}, error) {
-			value := reflect.ValueOf(collection)
-			if value.Type().Kind() != reflect.Slice {
-				return nil, fmt.Errorf("input must be a slice")
-			}
-			if value.Len() == 0 {
-				return nil, nil
-			}
-			return value.Index(0).Interface(), nil
-		},
-		"at": func(index int, collection interface{}) (interface{}, error) {
-			value := reflect.ValueOf(collection)
-			if value.Type().Kind() != reflect.Slice {
-				return nil, fmt.Errorf("input must be a slice")
-			}
-			if value.Len() == 0 {
-				return nil, nil
-			}
-			return value.Index(index).Interface(), nil
-		},
-		"last": func(collection interface{}) (interface{}, error) {
-			value := reflect.ValueOf(collection)
-			if value.Type().Kind() != reflect.Slice {
-				return nil, fmt.Errorf("input must be a slice")
-			}
-			if value.Len() == 0 {
-				return nil, nil
-			}
-			return value.Index(value.Len() - 1).Interface(), nil
-		},
-		"join": func(sep string, collection interface{}) (string, error) {
-			value := reflect.ValueOf(collection)
-			if value.Type().Kind() != reflect.Slice {
-				return "", fmt.Errorf("input must be a slice")
-			}
-			if value.Len() == 0 {
-				return "", nil
-			}
-			values := make([]string, value.Len())
-			for i := 0; i < value.Len(); i++ {
-				values[i] = fmt.Sprintf("%v", value.Index(i).Interface())
-			}
-			return strings.Join(values, sep), nil
-		},
+		"slice": sliceCollection,
+		"first": first,
+		"at":    at,
+		"last":  last,
+		"join":  join,
+
+		// collections
+		"dict":      dict,
+		"list":      list,
+		"set":       set,
+		"unset":     unset,
+		"merge":     merge,
+		"keys":      keys,
+		"values":    values,
+		"pluck":     pluck,
+		"append":    appendValue,
+		"prepend":   prependValue,
+		"uniq":      uniq,
+		"without":   without,
+		"has":       has,
+		"sortAlpha": sortAlpha,
+		"sortBy":    sortBy,
+		"reverse":   reverse,
+		"compact":   compact,
+		"groupBy":   groupBy,
 
 		// string tests
 		"has_suffix": func(suffix, v string) bool {
@@ -456,6 +660,61 @@ func (t *Template) baseFuncMap() texttemplate.FuncMap {
 		"prerelease": func(v *Semver) string {
 			return string(v.PreRelease)
 		},
+		"semver_compare": func(a, b interface{}) (int, error) {
+			versionA, err := toSemver(a)
+			if err != nil {
+				return 0, err
+			}
+			versionB, err := toSemver(b)
+			if err != nil {
+				return 0, err
+			}
+			return versionA.Compare(*versionB), nil
+		},
+		"semver_bump": func(part string, v interface{}) (*Semver, error) {
+			version, err := toSemver(v)
+			if err != nil {
+				return nil, err
+			}
+			switch part {
+			case "major":
+				version.BumpMajor()
+			case "minor":
+				version.BumpMinor()
+			case "patch":
+				version.BumpPatch()
+			default:
+				return nil, fmt.Errorf("semver_bump: unknown part `%s`; must be major, minor or patch", part)
+			}
+			return version, nil
+		},
+		"semver_sort": func(collection interface{}) (Semvers, error) {
+			versions, err := toSemvers(collection)
+			if err != nil {
+				return nil, err
+			}
+			Sort(versions)
+			return versions, nil
+		},
+		"semver_latest": func(collection interface{}) (*Semver, error) {
+			versions, err := toSemvers(collection)
+			if err != nil || len(versions) == 0 {
+				return nil, err
+			}
+			Sort(versions)
+			return versions[len(versions)-1], nil
+		},
+		"semver_satisfies": func(constraint string, v interface{}) (bool, error) {
+			parsed, err := NewConstraint(constraint)
+			if err != nil {
+				return false, err
+			}
+			version, err := toSemver(v)
+			if err != nil {
+				return false, err
+			}
+			return parsed.Check(version), nil
+		},
 
 		"yaml": func(v interface{}) (string, error) {
 			data, err := yaml.Marshal(v)
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blendlabs/template"
+)
+
+// processDir renders a directory tree of templates into dst, mirroring the layout of src.
+// Files carrying the template suffix are rendered and written without it, every other file is copied verbatim.
+func processDir(src, dst, suffix string, prepare func(*template.Template) (*template.Template, error)) error {
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// if the output directory lives inside the source tree, don't render our own output.
+		if absPath, err := filepath.Abs(path); err == nil && absPath == absDst {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+
+		if name, isTemplate := stripTemplateSuffix(info.Name(), suffix); isTemplate {
+			return renderFile(path, filepath.Join(filepath.Dir(target), name), info.Mode(), prepare)
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+// stripTemplateSuffix returns the output name for a file and if it is a template.
+// The suffix may either end the name (`foo.yml.template`) or precede the extension (`foo.template.yml`).
+func stripTemplateSuffix(name, suffix string) (string, bool) {
+	if len(suffix) == 0 {
+		return name, false
+	}
+	if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+		return strings.TrimSuffix(name, suffix), true
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if len(ext) > 0 && strings.HasSuffix(base, suffix) && len(base) > len(suffix) {
+		return strings.TrimSuffix(base, suffix) + ext, true
+	}
+	return name, false
+}
+
+func renderFile(src, dst string, mode os.FileMode, prepare func(*template.Template) (*template.Template, error)) error {
+	temp, err := template.NewFromFile(src)
+	if err != nil {
+		return err
+	}
+
+	temp, err = prepare(temp)
+	if err != nil {
+		return err
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err = temp.Process(buffer)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = buffer.WriteTo(f)
+	return err
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
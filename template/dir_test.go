@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/blendlabs/template"
+)
+
+func TestStripTemplateSuffix(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range []struct {
+		name, suffix, expected string
+		isTemplate             bool
+	}{
+		{"deployment.yml", ".template", "deployment.yml", false},
+		{"deployment.yml.template", ".template", "deployment.yml", true},
+		{"deployment.template.yml", ".template", "deployment.yml", true},
+		{"deployment.template", ".template", "deployment", true},
+		{".template", ".template", ".template", false},
+		{".template.yml", ".template", ".template.yml", false},
+		{"config.v1.template.json", ".template", "config.v1.json", true},
+		{"deployment.yml.template", "", "deployment.yml.template", false},
+		{"deployment.yml.tpl", ".tpl", "deployment.yml", true},
+	} {
+		name, isTemplate := stripTemplateSuffix(test.name, test.suffix)
+		assert.Equal(test.expected, name, test.name)
+		assert.Equal(test.isTemplate, isTemplate, test.name)
+	}
+}
+
+func TestProcessDir(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "template-dir")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	src := filepath.Join(root, "src")
+	dst := filepath.Join(root, "out", "build")
+	writeTestFile(t, filepath.Join(src, "deployment.template.yml"), `name: {{ .Var "name" }}`)
+	writeTestFile(t, filepath.Join(src, "nested", "deeper", "service.yml.template"), `service: {{ .Var "name" }}`)
+	writeTestFile(t, filepath.Join(src, "nested", "static.yml"), `name: {{ .Var "name" }}`)
+	assert.Nil(os.MkdirAll(filepath.Join(src, "empty"), 0755))
+
+	err = processDir(src, dst, ".template", func(temp *template.Template) (*template.Template, error) {
+		return temp.WithVar("name", "test-service"), nil
+	})
+	assert.Nil(err)
+
+	assert.Equal("name: test-service", readTestFile(t, filepath.Join(dst, "deployment.yml")))
+	assert.Equal("service: test-service", readTestFile(t, filepath.Join(dst, "nested", "deeper", "service.yml")))
+	assert.Equal(`name: {{ .Var "name" }}`, readTestFile(t, filepath.Join(dst, "nested", "static.yml")))
+
+	info, err := os.Stat(filepath.Join(dst, "empty"))
+	assert.Nil(err)
+	assert.True(info.IsDir())
+
+	_, err = os.Stat(filepath.Join(dst, "deployment.template.yml"))
+	assert.True(os.IsNotExist(err))
+}
+
+func TestProcessDirSkipsOutputInsideSource(t *testing.T) {
+	assert := assert.New(t)
+
+	src, err := ioutil.TempDir("", "template-dir")
+	assert.Nil(err)
+	defer os.RemoveAll(src)
+
+	writeTestFile(t, filepath.Join(src, "config.yml.template"), `{{ .Var "name" }}`)
+	writeTestFile(t, filepath.Join(src, "build", "stale.yml.template"), `{{ .Var "missing" }}`)
+
+	err = processDir(src, filepath.Join(src, "build"), ".template", func(temp *template.Template) (*template.Template, error) {
+		return temp.WithVar("name", "test-service"), nil
+	})
+	assert.Nil(err)
+	assert.Equal("test-service", readTestFile(t, filepath.Join(src, "build", "config.yml")))
+}
+
+func writeTestFile(t *testing.T, path, contents string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(contents)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/blendlabs/template"
+	"gopkg.in/yaml.v2"
+)
+
+// runEncrypt implements the `encrypt` subcommand, which encrypts a vars file so it can be committed,
+// or generates a new key.
+func runEncrypt(args []string) {
+	flags := flag.NewFlagSet("encrypt", flag.ExitOnError)
+
+	var file string
+	flags.StringVar(&file, "f", "-", "Vars file to encrypt; if \"-\", will read from os.Stdin")
+
+	var outFile string
+	flags.StringVar(&outFile, "o", "", "Output file; defaults to os.Stdout")
+
+	var keyFile string
+	flags.StringVar(&keyFile, "key-file", "", "File holding the encryption key; defaults to the TEMPLATE_KEY env variable")
+
+	var values bool
+	flags.BoolVar(&values, "values", false, "Encrypts each value rather than the whole file, leaving the keys readable")
+
+	var genKey bool
+	flags.BoolVar(&genKey, "gen-key", false, "Generates a new key instead of encrypting")
+
+	var keySize int
+	flags.IntVar(&keySize, "key-size", 32, "Size of a generated key in bytes; 16, 24 or 32")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s encrypt [-f <vars file>] [-o <output>] [-key-file <key file>] [-values]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s encrypt -gen-key [-o <key file>]\n\n", os.Args[0])
+		flags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample Usage:\n")
+		fmt.Fprintf(os.Stderr, "Generate a key: template encrypt -gen-key -o template.key\n")
+		fmt.Fprintf(os.Stderr, "Encrypt a vars file: template encrypt -key-file template.key -f secrets.yml -o secrets.enc.yml\n")
+		fmt.Fprintf(os.Stderr, "Encrypt only the values: template encrypt -key-file template.key -f secrets.yml -values -o secrets.enc.yml\n")
+	}
+	flags.Parse(args)
+
+	if genKey {
+		key := template.Helpers{}.CreateKey(keySize)
+		if _, err := template.ParseKey(key); err != nil {
+			log.Fatal(err)
+		}
+		if err := writeSecretOutput(outFile, []byte(key+"\n")); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	key, err := loadKey(keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contents, err := readInput(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if template.IsEncrypted(string(contents)) {
+		log.Fatalf("%s is already encrypted", file)
+	}
+
+	var output []byte
+	if values {
+		vars, err := unmarshalVars(file, contents)
+		if err != nil {
+			log.Fatal(err)
+		}
+		encrypted, err := template.EncryptVars(key, vars)
+		if err != nil {
+			log.Fatal(err)
+		}
+		output, err = marshalVars(file, encrypted)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		output, err = template.EncryptFile(key, contents)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := writeSecretOutput(outFile, output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runDecrypt implements the `decrypt` subcommand, the reverse of `encrypt`.
+func runDecrypt(args []string) {
+	flags := flag.NewFlagSet("decrypt", flag.ExitOnError)
+
+	var file string
+	flags.StringVar(&file, "f", "-", "Vars file to decrypt; if \"-\", will read from os.Stdin")
+
+	var outFile string
+	flags.StringVar(&outFile, "o", "", "Output file; defaults to os.Stdout")
+
+	var keyFile string
+	flags.StringVar(&keyFile, "key-file", "", "File holding the encryption key; defaults to the TEMPLATE_KEY env variable")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s decrypt [-f <vars file>] [-o <output>] [-key-file <key file>]\n\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	key, err := loadKey(keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contents, err := readInput(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var output []byte
+	if template.IsEncrypted(string(contents)) {
+		output, err = template.DecryptFile(key, contents)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		vars, err := unmarshalVars(file, contents)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !template.HasEncryptedValues(vars) {
+			log.Fatalf("%s is not encrypted", file)
+		}
+		decrypted, err := template.DecryptVars(key, vars)
+		if err != nil {
+			log.Fatal(err)
+		}
+		output, err = marshalVars(file, decrypted)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := writeSecretOutput(outFile, output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(path)
+}
+
+func unmarshalVars(path string, contents []byte) (template.Vars, error) {
+	vars := template.Vars{}
+	var err error
+	if strings.HasSuffix(path, ".json") {
+		err = json.Unmarshal(contents, &vars)
+	} else {
+		err = yaml.Unmarshal(contents, &vars)
+	}
+	return vars, err
+}
+
+func marshalVars(path string, vars template.Vars) ([]byte, error) {
+	if strings.HasSuffix(path, ".json") {
+		return json.MarshalIndent(vars, "", "  ")
+	}
+	return yaml.Marshal(vars)
+}
+
+// writeSecretOutput writes to a file only the owner can read, or os.Stdout if no file is given.
+func writeSecretOutput(path string, contents []byte) error {
+	if len(path) == 0 {
+		_, err := os.Stdout.Write(contents)
+		return err
+	}
+	return ioutil.WriteFile(path, contents, 0600)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/blendlabs/template"
+)
+
+// funcName matches the names text/template accepts for functions.
+var funcName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// CommandFuncs are template functions implemented by external commands, in the form `name=command`.
+type CommandFuncs []string
+
+// Set sets the value.
+func (v *CommandFuncs) Set(value string) error {
+	pieces := strings.SplitN(value, "=", 2)
+	if len(pieces) < 2 || len(strings.TrimSpace(pieces[1])) == 0 {
+		return fmt.Errorf("`%s` is not in the form name=command", value)
+	}
+	if !funcName.MatchString(pieces[0]) {
+		return fmt.Errorf("invalid function name `%s`", pieces[0])
+	}
+	*v = append(*v, value)
+	return nil
+}
+
+func (v *CommandFuncs) String() string {
+	return "Template functions implemented by external commands"
+}
+
+// Funcs returns the template functions.
+func (v CommandFuncs) Funcs() template.FuncMap {
+	funcs := template.FuncMap{}
+	for _, value := range v {
+		pieces := strings.SplitN(value, "=", 2)
+		funcs[pieces[0]] = commandFunc(pieces[0], strings.Fields(pieces[1]))
+	}
+	return funcs
+}
+
+// commandFunc returns a template function that runs a command with the function's arguments written to its
+// stdin as a json array, and returns what the command prints with any trailing newline removed.
+// The command is run directly, not through a shell.
+func commandFunc(name string, command []string) func(args ...interface{}) (string, error) {
+	return func(args ...interface{}) (string, error) {
+		if args == nil {
+			args = []interface{}{}
+		}
+		input, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("%s: cannot encode arguments: %v", name, err)
+		}
+
+		stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+				return "", fmt.Errorf("%s: %v: %s", name, err, message)
+			}
+			return "", fmt.Errorf("%s: %v", name, err)
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(stdout.String(), "\n"), "\r"), nil
+	}
+}
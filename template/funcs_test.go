@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/blendlabs/template"
+)
+
+func TestCommandFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	echo := commandFunc("echo", []string{"cat"})
+	output, err := echo("foo", 3, true, map[string]interface{}{"bar": []interface{}{"baz"}})
+	assert.Nil(err)
+	assert.Equal(`["foo",3,true,{"bar":["baz"]}]`, output)
+
+	output, err = echo()
+	assert.Nil(err)
+	assert.Equal(`[]`, output)
+
+	output, err = commandFunc("lines", []string{"sh", "-c", "printf 'a\\nb\\n'"})()
+	assert.Nil(err)
+	assert.Equal("a\nb", output)
+
+	_, err = commandFunc("fail", []string{"sh", "-c", "echo 'no such user' >&2; exit 3"})("root")
+	assert.NotNil(err)
+	assert.Equal("fail: exit status 3: no such user", err.Error())
+
+	_, err = commandFunc("quiet", []string{"sh", "-c", "exit 1"})()
+	assert.NotNil(err)
+	assert.Equal("quiet: exit status 1", err.Error())
+}
+
+func TestCommandFuncs(t *testing.T) {
+	assert := assert.New(t)
+
+	var funcs CommandFuncs
+	assert.Nil(funcs.Set("echo=cat"))
+	for _, value := range []string{"echo", "echo=", "echo=  ", "bad-name=cat", "1st=cat", "=cat"} {
+		assert.NotNil(funcs.Set(value), value)
+	}
+	assert.Len(funcs, 1)
+
+	buffer := bytes.NewBuffer(nil)
+	err := template.New().
+		WithBody(`{{ echo "foo" 3 }}`).
+		WithFuncs(funcs.Funcs()).
+		Process(buffer)
+	assert.Nil(err)
+	assert.Equal(`["foo",3]`, buffer.String())
+}
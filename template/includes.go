@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// includeFile is a sub template read from disk.
+type includeFile struct {
+	// name is the path the include is registered under, relative to the directory it was found in.
+	name string
+	path string
+	body string
+}
+
+// loadIncludes resolves include paths and globs and reads them.
+// Each pattern is looked up relative to the working directory first, then in each search path in order.
+func loadIncludes(patterns, searchPaths []string) ([]includeFile, error) {
+	var includes []includeFile
+	seen := map[string]bool{}
+
+	for _, pattern := range patterns {
+		found, err := resolveInclude(pattern, searchPaths)
+		if err != nil {
+			return nil, err
+		}
+		for _, include := range found {
+			if seen[include.path] {
+				continue
+			}
+			seen[include.path] = true
+
+			contents, err := ioutil.ReadFile(include.path)
+			if err != nil {
+				return nil, err
+			}
+			include.body = string(contents)
+			includes = append(includes, include)
+		}
+	}
+	return includes, nil
+}
+
+func resolveInclude(pattern string, searchPaths []string) ([]includeFile, error) {
+	dirs := []string{""}
+	if !filepath.IsAbs(pattern) {
+		dirs = append(dirs, searchPaths...)
+	}
+
+	isGlob := strings.ContainsAny(pattern, "*?[")
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern `%s`: %v", pattern, err)
+		}
+
+		var found []includeFile
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || info.IsDir() {
+				continue
+			}
+			name := match
+			if len(dir) > 0 {
+				if rel, err := filepath.Rel(dir, match); err == nil {
+					name = rel
+				}
+			}
+			found = append(found, includeFile{name: filepath.ToSlash(name), path: match})
+		}
+		if len(found) > 0 {
+			return found, nil
+		}
+	}
+
+	if isGlob {
+		return nil, fmt.Errorf("include pattern `%s` matched no files", pattern)
+	}
+	return nil, fmt.Errorf("include `%s` not found", pattern)
+}
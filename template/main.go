@@ -13,10 +13,13 @@ import (
 
 	"strconv"
 
+	"regexp"
 	"runtime"
 
 	"bytes"
 
+	"time"
+
 	"github.com/blendlabs/template"
 	"gopkg.in/yaml.v2"
 )
@@ -39,12 +42,83 @@ func (v *Includes) String() string {
 	return "Files to include as sub templates"
 }
 
-// Variables are a list of commandline variables.
-type Variables []string
+// IncludePaths are directories searched for includes.
+type IncludePaths []string
+
+// Set sets the value.
+func (v *IncludePaths) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
+func (v *IncludePaths) String() string {
+	return "Directories searched for includes"
+}
+
+// AllowedFiles are directories a sandboxed template can read files from.
+type AllowedFiles []string
+
+// Set sets the value.
+func (v *AllowedFiles) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
+func (v *AllowedFiles) String() string {
+	return "Directories a sandboxed template can read files from"
+}
+
+// AllowedEnv are env variable names, or prefixes ending in `*`, a sandboxed template can read.
+type AllowedEnv []string
+
+// Set sets the value.
+func (v *AllowedEnv) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
+func (v *AllowedEnv) String() string {
+	return "Env variables a sandboxed template can read"
+}
+
+// VarsFiles are a collection of vars files, later files are merged over earlier ones.
+type VarsFiles []string
+
+// Set sets the value.
+func (v *VarsFiles) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
+func (v *VarsFiles) String() string {
+	return "Vars files to process"
+}
+
+// Variables are the variables set by every `-var*` flag, kept in commandline order so that the last flag wins
+// when the same key is set more than once.
+type Variables []variable
+
+// variable is a commandline variable. Its value is parsed when the template is rendered, so `-var-file` contents
+// are re-read in watch mode.
+type variable struct {
+	key   string
+	raw   string
+	parse func(string) (interface{}, error)
+	file  bool
+}
+
+// variableValue is a parsed commandline variable.
+type variableValue struct {
+	key   string
+	value interface{}
+}
 
 // Set sets a variable.
 func (v *Variables) Set(value string) error {
-	*v = append(*v, value)
+	pieces := strings.SplitN(value, "=", 2)
+	if len(pieces) > 1 {
+		*v = append(*v, variable{key: pieces[0], raw: pieces[1], parse: parseStringVariable})
+	}
 	return nil
 }
 
@@ -52,55 +126,178 @@ func (v *Variables) String() string {
 	return "Variable values to set in the template"
 }
 
-// Values returns the map of values.
-func (v *Variables) Values() (values map[string]string) {
-	values = map[string]string{}
+// Paths returns the paths of the files read by `-var-file`.
+func (v *Variables) Paths() (paths []string) {
+	for _, variable := range *v {
+		if variable.file {
+			paths = append(paths, variable.raw)
+		}
+	}
+	return
+}
 
-	for _, val := range *v {
-		pieces := strings.SplitN(val, "=", 2)
-		if len(pieces) > 1 {
-			values[pieces[0]] = pieces[1]
+// Values returns the parsed variables in commandline order.
+func (v *Variables) Values() (values []variableValue, err error) {
+	for _, variable := range *v {
+		var value interface{}
+		value, err = variable.parse(variable.raw)
+		if err != nil {
+			err = fmt.Errorf("invalid value for `%s`: %v", variable.key, err)
+			return
 		}
+		values = append(values, variableValue{key: variable.key, value: value})
 	}
 	return
 }
 
+// add validates a `key=value` flag value, unless it names a file, and appends it.
+func (v *Variables) add(value string, parse func(string) (interface{}, error), file bool) error {
+	pieces := strings.SplitN(value, "=", 2)
+	if len(pieces) < 2 || len(pieces[0]) == 0 {
+		return fmt.Errorf("`%s` is not in the form key=value", value)
+	}
+	if !file {
+		if _, err := parse(pieces[1]); err != nil {
+			return fmt.Errorf("invalid value for `%s`: %v", pieces[0], err)
+		}
+	}
+	*v = append(*v, variable{key: pieces[0], raw: pieces[1], parse: parse, file: file})
+	return nil
+}
+
 // Numbers represent float typed variables.
-type Numbers []string
+type Numbers struct{ *Variables }
 
 // Set sets a variable.
-func (n *Numbers) Set(value string) error {
-	*n = append(*n, value)
-	return nil
+func (n Numbers) Set(value string) error {
+	return n.add(value, parseFloatVariable, false)
 }
 
-func (n *Numbers) String() string {
+func (n Numbers) String() string {
 	return "Number variable values to set in the template"
 }
 
-// Values returns the map of values.
-func (n *Numbers) Values() (values map[string]interface{}, err error) {
-	values = map[string]interface{}{}
+// Integers represent int typed variables.
+type Integers struct{ *Variables }
 
-	var value float64
-	for _, val := range *n {
-		pieces := strings.SplitN(val, "=", 2)
-		if len(pieces) > 1 {
-			value, err = strconv.ParseFloat(pieces[1], 64)
-			if err != nil {
-				return
-			}
-			values[pieces[0]] = value
+// Set sets a variable.
+func (i Integers) Set(value string) error {
+	return i.add(value, parseIntVariable, false)
+}
+
+func (i Integers) String() string {
+	return "Integer variable values to set in the template"
+}
+
+// Booleans represent bool typed variables.
+type Booleans struct{ *Variables }
+
+// Set sets a variable.
+func (b Booleans) Set(value string) error {
+	return b.add(value, parseBoolVariable, false)
+}
+
+func (b Booleans) String() string {
+	return "Boolean variable values to set in the template"
+}
+
+// Literals represent variables given as json or yaml literals, e.g. lists and maps.
+type Literals struct{ *Variables }
+
+// Set sets a variable.
+func (l Literals) Set(value string) error {
+	return l.add(value, parseLiteralVariable, false)
+}
+
+func (l Literals) String() string {
+	return "JSON or YAML literal variable values to set in the template"
+}
+
+// FileVariables represent variables whose values are the contents of a file.
+type FileVariables struct{ *Variables }
+
+// Set sets a variable.
+func (f FileVariables) Set(value string) error {
+	return f.add(value, parseFileVariable, true)
+}
+
+func (f FileVariables) String() string {
+	return "File contents to set as variable values in the template"
+}
+
+func parseStringVariable(raw string) (interface{}, error) {
+	return raw, nil
+}
+
+func parseFloatVariable(raw string) (interface{}, error) {
+	return strconv.ParseFloat(raw, 64)
+}
+
+func parseIntVariable(raw string) (interface{}, error) {
+	return strconv.ParseInt(raw, 10, 64)
+}
+
+func parseBoolVariable(raw string) (interface{}, error) {
+	return strconv.ParseBool(raw)
+}
+
+func parseLiteralVariable(raw string) (interface{}, error) {
+	var value interface{}
+	err := yaml.Unmarshal([]byte(raw), &value)
+	return value, err
+}
+
+func parseFileVariable(path string) (interface{}, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return string(contents), nil
+}
+
+// loadTemplate reads a template from a file, or from os.Stdin if the path is "-".
+func loadTemplate(path string) (*template.Template, error) {
+	if path == "-" {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
 		}
+		return template.New().WithBody(string(contents)), nil
 	}
-	return
+	return template.NewFromFile(path)
 }
 
-func loadVarsFile(path string) (map[string]interface{}, error) {
+// loadVarsFiles loads and deep merges vars files in order.
+// Encrypted files and values are decrypted with the key from the key file, or the `TEMPLATE_KEY` env variable,
+// and marked sensitive.
+func loadVarsFiles(paths []string, lists template.ListMergeStrategy, keyFile string) (template.Vars, error) {
+	vars := template.Vars{}
+	for _, path := range paths {
+		fileVars, err := loadVarsFile(path, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		vars = template.MergeVars(vars, fileVars, lists)
+	}
+	return vars, nil
+}
+
+func loadVarsFile(path, keyFile string) (map[string]interface{}, error) {
 	contents, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	encrypted := template.IsEncrypted(string(contents))
+	if encrypted {
+		key, err := loadKey(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s is encrypted: %v", path, err)
+		}
+		contents, err = template.DecryptFile(key, contents)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
 	output := map[string]interface{}{}
 	if strings.HasSuffix(path, ".json") {
 		err = json.Unmarshal(contents, &output)
@@ -113,24 +310,127 @@ func loadVarsFile(path string) (map[string]interface{}, error) {
 			return nil, err
 		}
 	}
+	if encrypted {
+		return template.MarkSensitive(output), nil
+	}
+	if template.HasEncryptedValues(output) {
+		key, err := loadKey(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s has encrypted values: %v", path, err)
+		}
+		output, err = template.DecryptSensitiveVars(key, output)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
 	return output, nil
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "vars":
+			runVars(os.Args[2:])
+			return
+		case "encrypt":
+			runEncrypt(os.Args[2:])
+			return
+		case "decrypt":
+			runDecrypt(os.Args[2:])
+			return
+		case "semver":
+			runSemver(os.Args[2:])
+			return
+		}
+	}
+
 	var templateFile string
 	flag.StringVar(&templateFile, "f", "", "Template file to process; if \"-\", will read from os.Stdin")
 
 	var includes Includes
-	flag.Var(&includes, "i", "Files to include as sub templates")
+	flag.Var(&includes, "i", "Files to include as sub templates, registered under their relative path; can be a glob such as 'partials/*.tpl'")
+
+	var includePaths IncludePaths
+	flag.Var(&includePaths, "I", "Directories searched for -i includes not found relative to the working directory; can be repeated")
+
+	var varsFiles VarsFiles
+	flag.Var(&varsFiles, "vars", "Vars files to process; can be repeated, later files are deep merged over earlier ones")
 
-	var varsFile string
-	flag.StringVar(&varsFile, "vars", "", "Vars file to process")
+	var mergeLists string
+	flag.StringVar(&mergeLists, "merge-lists", "replace", "How lists are merged across vars files; either \"replace\" or \"append\"")
 
 	var outFile string
 	flag.StringVar(&outFile, "o", "", "Output file")
 
+	var splitDocs string
+	flag.StringVar(&splitDocs, "split-docs", "", "Splits the rendered yaml documents into individual files in this directory")
+
+	var splitPattern string
+	flag.StringVar(&splitPattern, "split-pattern", template.DefaultDocumentNamePattern, "File name pattern for -split-docs; placeholders are paths into each document, or {{index}}")
+
+	var sourceDir string
+	flag.StringVar(&sourceDir, "dir", "", "Directory of templates to process; requires -out")
+
+	var outDir string
+	flag.StringVar(&outDir, "out", "", "Output directory when processing a directory with -dir")
+
+	var templateSuffix string
+	flag.StringVar(&templateSuffix, "suffix", ".template", "Suffix that marks files as templates in -dir mode; it is stripped from the output file name")
+
 	var variables Variables
-	flag.Var(&variables, "var", "Variables in the form --var=foo=bar")
+	flag.Var(&variables, "var", "Variables in the form --var=foo=bar; the key can be a dotted path such as --var=db.hosts[0]=localhost")
+
+	flag.Var(FileVariables{&variables}, "var-file", "Variables set to the contents of a file in the form --var-file=foo=path/to/file")
+
+	flag.Var(Literals{&variables}, "var-json", "Variables given as JSON or YAML literals in the form --var-json='foo=[\"bar\",\"baz\"]'")
+
+	flag.Var(Booleans{&variables}, "var-bool", "Boolean variables in the form --var-bool=foo=true")
+
+	flag.Var(Integers{&variables}, "var-int", "Integer variables in the form --var-int=foo=3")
+
+	flag.Var(Numbers{&variables}, "var-float", "Number variables in the form --var-float=foo=3.14")
+
+	var schemaFile string
+	flag.StringVar(&schemaFile, "schema", "", "JSON or YAML schema the vars are validated against; its defaults are set for missing vars")
+
+	var validate string
+	flag.StringVar(&validate, "validate", "", "Validates the rendered output before writing it; either \"yaml\" or \"json\"")
+
+	var strict bool
+	flag.BoolVar(&strict, "strict", false, "Renders to completion and reports every missing variable at once")
+
+	var sprig bool
+	flag.BoolVar(&sprig, "sprig", false, "Adds Sprig (Helm) compatible functions such as default, required, dict, toYaml and nindent; they replace built-in functions of the same name")
+
+	var commandFuncs CommandFuncs
+	flag.Var(&commandFuncs, "func", "Template function implemented by a command in the form --func=name=command; the arguments are written to its stdin as a json array and it prints the result")
+
+	var secretProviders SecretProviders
+	flag.Var(&secretProviders, "secrets", "Provider for the secret function: env[:<PREFIX>], file:<PATH> or vault:<ADDRESS>; can be repeated, providers are tried in order")
+
+	var keyFile string
+	flag.StringVar(&keyFile, "key-file", "", "File holding the base64 encryption key for encrypted vars and secrets files; defaults to the TEMPLATE_KEY env variable")
+
+	var sensitivePattern string
+	flag.StringVar(&sensitivePattern, "sensitive-pattern", "", "Regular expression matching the names of vars whose values are redacted from errors and -dry-run; \"default\" matches names like password, secret and token")
+
+	var dryRun bool
+	flag.BoolVar(&dryRun, "dry-run", false, "Prints the resolved vars, with sensitive values redacted, instead of rendering")
+
+	var sandbox bool
+	flag.BoolVar(&sandbox, "sandbox", false, "Restricts the files and env variables the template can read to those allowed by -allow-file and -allow-env")
+
+	var allowedFiles AllowedFiles
+	flag.Var(&allowedFiles, "allow-file", "Directory a sandboxed template can read files from; can be repeated, implies -sandbox")
+
+	var allowedEnv AllowedEnv
+	flag.Var(&allowedEnv, "allow-env", "Env variable a sandboxed template can read, or a prefix such as 'APP_*'; can be repeated, implies -sandbox")
+
+	var watchFlag bool
+	flag.BoolVar(&watchFlag, "watch", false, "Re-renders whenever the template, an include, a vars file or a file read by the template changes")
+
+	var watchInterval time.Duration
+	flag.DurationVar(&watchInterval, "watch-interval", time.Second, "How often to check for changes in watch mode")
 
 	var help bool
 	flag.BoolVar(&help, "help", false, "Shows this usage message")
@@ -146,10 +446,25 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Find more information at https://github.com/blendlabs/template\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n")
 		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nSubcommands:\n")
+		fmt.Fprintf(os.Stderr, "  vars     Lists the variables, env variables and files a template references\n")
+		fmt.Fprintf(os.Stderr, "  encrypt  Encrypts a vars file, or its values, or generates a key\n")
+		fmt.Fprintf(os.Stderr, "  decrypt  Decrypts a vars file encrypted with encrypt\n")
+		fmt.Fprintf(os.Stderr, "  semver   Bumps, compares, sorts and checks semantic versions\n")
 		fmt.Fprintf(os.Stderr, "\nExample Usage:\n")
 		fmt.Fprintf(os.Stderr, "Read a template file: \"template -f template.yml\"\n")
 		fmt.Fprintf(os.Stderr, "Read a template from stdin: \"echo '{{ .Var \"foo\" }}' | template -f -\"\n")
 		fmt.Fprintf(os.Stderr, "Specify a variable: template -f config.yml --var=foo=bar\n")
+		fmt.Fprintf(os.Stderr, "Specify typed variables: template -f config.yml --var-int=replicas=3 --var-json='ports=[80,443]'\n")
+		fmt.Fprintf(os.Stderr, "Include partials: template -f config.yml -I templates -i 'partials/*.tpl'\n")
+		fmt.Fprintf(os.Stderr, "Layer vars files: template -f config.yml -vars base.yml -vars env/prod.yml\n")
+		fmt.Fprintf(os.Stderr, "Render a directory of templates: template -dir manifests -out build/manifests\n")
+		fmt.Fprintf(os.Stderr, "Split documents into files: template -f manifests.yml -split-docs build/ -split-pattern '{{kind}}-{{metadata.name}}.yml'\n")
+		fmt.Fprintf(os.Stderr, "Render a Helm style template: template -f deployment.yml -vars values.yml -sprig\n")
+		fmt.Fprintf(os.Stderr, "Add a function implemented by a script: template -f config.yml --func='checksum=./scripts/checksum.sh'\n")
+		fmt.Fprintf(os.Stderr, "Read secrets from vault, falling back to env: VAULT_TOKEN=... template -f config.yml -secrets vault:https://vault:8200 -secrets env:SECRET_\n")
+		fmt.Fprintf(os.Stderr, "Render an untrusted template: template -f config.yml -allow-file ./config -allow-env 'APP_*'\n")
+		fmt.Fprintf(os.Stderr, "Re-render on changes: template -f config.yml -vars vars.yml -o config.out.yml --watch\n")
 	}
 
 	flag.Parse()
@@ -167,71 +482,180 @@ func main() {
 		os.Exit(0)
 	}
 
-	var temp *template.Template
-	var err error
-	if len(templateFile) > 0 && templateFile == "-" {
-		temp = template.New()
+	if watchFlag && templateFile == "-" {
+		log.Fatal("cannot watch a template read from os.Stdin")
+	}
+	if len(sourceDir) > 0 && len(outDir) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if len(sourceDir) == 0 && len(templateFile) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if len(splitDocs) > 0 && (len(outFile) > 0 || len(sourceDir) > 0) {
+		log.Fatal("-split-docs cannot be combined with -o or -dir")
+	}
+
+	// render loads every input, renders the template(s) and writes the output.
+	// It returns the paths of every file the render read, for watch mode.
+	render := func() (inputs []string, err error) {
+		inputs = append(inputs, includes...)
+		inputs = append(inputs, includePaths...)
+		inputs = append(inputs, varsFiles...)
+		inputs = append(inputs, variables.Paths()...)
+		inputs = append(inputs, secretProviders.Paths()...)
+		if len(schemaFile) > 0 {
+			inputs = append(inputs, schemaFile)
+		}
+		if len(sourceDir) > 0 {
+			inputs = append(inputs, sourceDir)
+		} else {
+			inputs = append(inputs, templateFile)
+		}
 
-		var contents []byte
-		contents, err = ioutil.ReadAll(os.Stdin)
+		includeFiles, err := loadIncludes(includes, includePaths)
 		if err != nil {
-			log.Fatal(err)
+			return
 		}
-		temp = temp.WithBody(string(contents))
-	} else if len(templateFile) > 0 {
-		temp, err = template.NewFromFile(templateFile)
+
+		lists, err := template.ParseListMergeStrategy(mergeLists)
 		if err != nil {
-			log.Fatal(err)
+			return
 		}
-	} else {
-		flag.Usage()
-		os.Exit(1)
-	}
 
-	if len(includes) > 0 {
-		for _, include := range includes {
+		vars, err := loadVarsFiles(varsFiles, lists, keyFile)
+		if err != nil {
+			return
+		}
+
+		var schema *template.Schema
+		if len(schemaFile) > 0 {
 			var contents []byte
-			contents, err = ioutil.ReadFile(include)
+			contents, err = ioutil.ReadFile(schemaFile)
 			if err != nil {
-				log.Fatal(err)
+				return
+			}
+			schema, err = template.ParseSchema(contents)
+			if err != nil {
+				return
 			}
-			temp = temp.WithInclude(string(contents))
 		}
-	}
 
-	if len(varsFile) > 0 {
-		vars, err := loadVarsFile(varsFile)
+		var validator template.Validator
+		if len(validate) > 0 {
+			validator, err = template.ParseValidator(validate)
+			if err != nil {
+				return
+			}
+		}
+
+		// commandline variables are applied after the vars files, in commandline order.
+		overrides, err := variables.Values()
+		if err != nil {
+			return
+		}
+
+		secrets, err := loadSecretProviders(secretProviders, keyFile)
 		if err != nil {
-			log.Fatal(err)
+			return
 		}
-		for key, value := range vars {
-			temp = temp.WithVar(key, value)
+
+		var sensitive *regexp.Regexp
+		if sensitivePattern == "default" {
+			sensitive = template.DefaultSensitivePattern
+		} else if len(sensitivePattern) > 0 {
+			sensitive, err = regexp.Compile(sensitivePattern)
+			if err != nil {
+				err = fmt.Errorf("invalid -sensitive-pattern: %v", err)
+				return
+			}
 		}
-	}
 
-	vars := variables.Values()
-	if len(vars) > 0 {
-		for key, value := range vars {
-			temp = temp.WithVar(key, value)
+		var templateSandbox *template.Sandbox
+		if sandbox || len(allowedFiles) > 0 || len(allowedEnv) > 0 {
+			templateSandbox = &template.Sandbox{FileRoots: allowedFiles, EnvAllow: allowedEnv}
 		}
-	}
 
-	buffer := bytes.NewBuffer(nil)
-	err = temp.Process(buffer)
-	if err != nil {
-		log.Fatal(err)
-	}
+		var prepared []*template.Template
+		defer func() {
+			for _, temp := range prepared {
+				inputs = append(inputs, temp.Files()...)
+			}
+		}()
+		prepare := func(temp *template.Template) (*template.Template, error) {
+			prepared = append(prepared, temp)
+			for _, include := range includeFiles {
+				temp = temp.WithNamedInclude(include.name, include.body)
+			}
+			for _, provider := range secrets {
+				temp = temp.WithSecretProvider(provider)
+			}
+			if sprig {
+				temp = temp.WithSprig()
+			}
+			temp = temp.WithFuncs(commandFuncs.Funcs()).WithStrict(strict).WithSchema(schema).WithValidator(validator).WithSandbox(templateSandbox).WithSensitivePattern(sensitive).WithVars(vars)
+			for _, override := range overrides {
+				if err := temp.SetVarPath(override.key, override.value); err != nil {
+					return nil, err
+				}
+			}
+			return temp, nil
+		}
+
+		if dryRun {
+			var temp *template.Template
+			temp, err = prepare(template.New())
+			if err != nil {
+				return
+			}
+			var contents []byte
+			contents, err = yaml.Marshal(temp.Vars())
+			if err != nil {
+				return
+			}
+			_, err = os.Stdout.Write(contents)
+			return
+		}
+
+		if len(sourceDir) > 0 {
+			err = processDir(sourceDir, outDir, templateSuffix, prepare)
+			return
+		}
 
-	if len(outFile) > 0 {
-		f, err := os.Create(outFile)
+		temp, err := loadTemplate(templateFile)
 		if err != nil {
-			log.Fatal(err)
+			return
 		}
-		_, err = buffer.WriteTo(f)
+		temp, err = prepare(temp)
 		if err != nil {
-			log.Fatal(err)
+			return
 		}
-	} else {
-		buffer.WriteTo(os.Stdout)
+
+		buffer := bytes.NewBuffer(nil)
+		err = temp.Process(buffer)
+		if err != nil {
+			return
+		}
+
+		if len(splitDocs) > 0 {
+			err = writeDocuments(splitDocs, splitPattern, buffer.Bytes())
+			return
+		}
+		if len(outFile) > 0 {
+			err = ioutil.WriteFile(outFile, buffer.Bytes(), 0666)
+			return
+		}
+		_, err = buffer.WriteTo(os.Stdout)
+		return
+	}
+
+	if watchFlag {
+		watch(watchInterval, []string{outFile, outDir, splitDocs}, render)
+		return
+	}
+
+	if _, err := render(); err != nil {
+		log.Fatal(err)
 	}
 }
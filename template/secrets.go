@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/blendlabs/template"
+)
+
+// keyEnvVar is the env variable the encryption key is read from when no key file is given.
+const keyEnvVar = "TEMPLATE_KEY"
+
+// SecretProviders are the providers for the `secret` function, in the form `env[:<PREFIX>]`, `file:<PATH>` or `vault:<ADDRESS>`.
+type SecretProviders []string
+
+// Set sets the value.
+func (v *SecretProviders) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
+func (v *SecretProviders) String() string {
+	return "Providers for the secret function"
+}
+
+// Paths returns the secrets files read by file providers.
+func (v SecretProviders) Paths() (paths []string) {
+	for _, spec := range v {
+		if kind, arg := splitSecretProvider(spec); kind == "file" {
+			paths = append(paths, arg)
+		}
+	}
+	return
+}
+
+// loadSecretProviders creates the providers in the order given.
+// The key for file providers is read from the key file, or the `TEMPLATE_KEY` env variable.
+// The token for vault providers is read from the `VAULT_TOKEN` env variable.
+func loadSecretProviders(specs []string, keyFile string) ([]template.SecretProvider, error) {
+	var providers []template.SecretProvider
+	for _, spec := range specs {
+		kind, arg := splitSecretProvider(spec)
+		switch kind {
+		case "env":
+			providers = append(providers, template.EnvSecretProvider{Prefix: arg})
+		case "file":
+			if len(arg) == 0 {
+				return nil, fmt.Errorf("invalid secret provider `%s`; a file provider needs a path", spec)
+			}
+			key, err := loadKey(keyFile)
+			if err != nil {
+				return nil, err
+			}
+			provider, err := template.NewFileSecretProvider(arg, key)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, provider)
+		case "vault":
+			address := arg
+			if len(address) == 0 {
+				address = os.Getenv("VAULT_ADDR")
+			}
+			if len(address) == 0 {
+				return nil, fmt.Errorf("invalid secret provider `%s`; a vault provider needs an address or VAULT_ADDR", spec)
+			}
+			providers = append(providers, template.NewVaultSecretProvider(address, os.Getenv("VAULT_TOKEN")))
+		default:
+			return nil, fmt.Errorf("invalid secret provider `%s`; must be `env`, `file` or `vault`", spec)
+		}
+	}
+	return providers, nil
+}
+
+func splitSecretProvider(spec string) (kind, arg string) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return parts[0], ""
+}
+
+// loadKey reads the encryption key from a key file, or the `TEMPLATE_KEY` env variable if no key file is given.
+func loadKey(keyFile string) ([]byte, error) {
+	if len(keyFile) == 0 {
+		encoded := os.Getenv(keyEnvVar)
+		if len(encoded) == 0 {
+			return nil, fmt.Errorf("no encryption key; use -key-file or set %s", keyEnvVar)
+		}
+		return template.ParseKey(encoded)
+	}
+	contents, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := template.ParseKey(string(contents))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", keyFile, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/blendlabs/template"
+)
+
+// Exit codes of the `semver` subcommand; false answers and errors are distinct so they can be told apart in shell conditionals.
+const (
+	semverExitTrue  = 0
+	semverExitFalse = 1
+	semverExitError = 2
+)
+
+// runSemver implements the `semver` subcommand, which bumps, compares, sorts and checks versions for release scripts.
+func runSemver(args []string) {
+	os.Exit(semverMain(args, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// semverMain runs a `semver` command and returns its exit code.
+func semverMain(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		semverUsage(stderr)
+		return semverExitError
+	}
+
+	command := semverCommand{stdin: stdin, stdout: stdout, stderr: stderr, log: log.New(stderr, "", log.LstdFlags)}
+	switch args[0] {
+	case "bump":
+		return command.bump(args[1:])
+	case "compare":
+		return command.compare(args[1:])
+	case "sort":
+		return command.sort(args[1:])
+	case "satisfies":
+		return command.satisfies(args[1:])
+	}
+	semverUsage(stderr)
+	return semverExitError
+}
+
+func semverUsage(stderr io.Writer) {
+	fmt.Fprintf(stderr, "Usage: %s semver <command> [flags] <args>\n\n", os.Args[0])
+	fmt.Fprintf(stderr, "Commands:\n")
+	fmt.Fprintf(stderr, "  bump       Prints a version with a part bumped\n")
+	fmt.Fprintf(stderr, "  compare    Prints -1, 0 or 1 if a version is lower than, equal to or higher than another\n")
+	fmt.Fprintf(stderr, "  sort       Prints the versions read from a file or os.Stdin, one per line and as given, lowest first\n")
+	fmt.Fprintf(stderr, "  satisfies  Exits 0 if every version satisfies a constraint, or 1 if any doesn't\n")
+	fmt.Fprintf(stderr, "\nVersions are parsed leniently, so `v1.2` is read as 1.2.0, unless -strict is given.\n")
+	fmt.Fprintf(stderr, "Every command exits 2 on an error.\n")
+	fmt.Fprintf(stderr, "\nExample Usage:\n")
+	fmt.Fprintf(stderr, "Bump a version: template semver bump minor 1.4.2\n")
+	fmt.Fprintf(stderr, "Bump a release candidate: template semver bump -preid rc prerelease 1.5.0-rc.1\n")
+	fmt.Fprintf(stderr, "Find the latest tag: git tag | template semver sort -skip-invalid | tail -n 1\n")
+	fmt.Fprintf(stderr, "Check a version: if template semver satisfies '^1.2' \"$VERSION\"; then ...; fi\n")
+}
+
+// semverCommand holds the streams `semver` commands read and write, so they can be run in tests.
+type semverCommand struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	log    *log.Logger
+}
+
+// flags returns a flag set for a command that reports errors instead of exiting.
+func (c semverCommand) flags(name, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet("semver "+name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: %s semver %s %s\n\n", os.Args[0], name, usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// fail logs an error and returns the error exit code, which unlike log.Fatal's can't be mistaken for a false answer.
+func (c semverCommand) fail(err error) int {
+	c.log.Print(err)
+	return semverExitError
+}
+
+func (c semverCommand) bump(args []string) int {
+	flags := c.flags("bump", "[-preid <prefix>] [-metadata <metadata>] <major|minor|patch|prerelease|release> <version>")
+
+	var preID string
+	flags.StringVar(&preID, "preid", "", "Pre-release prefix for the prerelease part, e.g. rc; defaults to the version's current prefix")
+
+	var metadata string
+	flags.StringVar(&metadata, "metadata", "", "Build metadata to add to the bumped version")
+
+	var strict bool
+	flags.BoolVar(&strict, "strict", false, "Requires versions to follow SemVer 2.0.0 exactly")
+
+	if err := flags.Parse(args); err != nil {
+		return semverExitError
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return semverExitError
+	}
+
+	version, err := parseSemver(flags.Arg(1), strict)
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := bumpSemver(version, flags.Arg(0), preID, metadata); err != nil {
+		return c.fail(err)
+	}
+	fmt.Fprintln(c.stdout, version)
+	return semverExitTrue
+}
+
+func (c semverCommand) compare(args []string) int {
+	flags := c.flags("compare", "<version> <version>")
+
+	var strict bool
+	flags.BoolVar(&strict, "strict", false, "Requires versions to follow SemVer 2.0.0 exactly")
+
+	if err := flags.Parse(args); err != nil {
+		return semverExitError
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return semverExitError
+	}
+
+	versionA, err := parseSemver(flags.Arg(0), strict)
+	if err != nil {
+		return c.fail(err)
+	}
+	versionB, err := parseSemver(flags.Arg(1), strict)
+	if err != nil {
+		return c.fail(err)
+	}
+	fmt.Fprintln(c.stdout, versionA.Compare(*versionB))
+	return semverExitTrue
+}
+
+func (c semverCommand) sort(args []string) int {
+	flags := c.flags("sort", "[-f <versions file>] [-r] [-skip-invalid]")
+
+	var file string
+	flags.StringVar(&file, "f", "-", "File of versions, one per line; if \"-\", will read from os.Stdin")
+
+	var reverse bool
+	flags.BoolVar(&reverse, "r", false, "Prints the highest version first")
+
+	var skipInvalid bool
+	flags.BoolVar(&skipInvalid, "skip-invalid", false, "Skips lines that aren't versions, such as other git tags, instead of failing")
+
+	var strict bool
+	flags.BoolVar(&strict, "strict", false, "Requires versions to follow SemVer 2.0.0 exactly")
+
+	if err := flags.Parse(args); err != nil {
+		return semverExitError
+	}
+
+	input := c.stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return c.fail(err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	lines, err := sortSemvers(input, strict, skipInvalid, reverse)
+	if err != nil {
+		return c.fail(err)
+	}
+	for _, line := range lines {
+		fmt.Fprintln(c.stdout, line.text)
+	}
+	return semverExitTrue
+}
+
+func (c semverCommand) satisfies(args []string) int {
+	flags := c.flags("satisfies", "<constraint> <version> [<version> ...]")
+
+	var strict bool
+	flags.BoolVar(&strict, "strict", false, "Requires versions to follow SemVer 2.0.0 exactly")
+
+	if err := flags.Parse(args); err != nil {
+		return semverExitError
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return semverExitError
+	}
+
+	constraint, err := template.NewConstraint(flags.Arg(0))
+	if err != nil {
+		return c.fail(err)
+	}
+
+	exitCode := semverExitTrue
+	for _, arg := range flags.Args()[1:] {
+		version, err := parseSemver(arg, strict)
+		if err != nil {
+			return c.fail(err)
+		}
+		if err := constraint.Validate(version); err != nil {
+			fmt.Fprintln(c.stderr, err)
+			exitCode = semverExitFalse
+		}
+	}
+	return exitCode
+}
+
+// bumpSemver bumps a part of a version, optionally adding build metadata.
+func bumpSemver(version *template.Semver, part, preID, metadata string) error {
+	switch part {
+	case "major":
+		version.BumpMajor()
+	case "minor":
+		version.BumpMinor()
+	case "patch":
+		version.BumpPatch()
+	case "prerelease":
+		version.BumpPreRelease(preID)
+	case "release":
+		version.Finalize()
+	default:
+		return fmt.Errorf("unknown part `%s`; must be major, minor, patch, prerelease or release", part)
+	}
+	if len(metadata) > 0 {
+		version.WithMetadata(metadata)
+	}
+	return nil
+}
+
+// semverLine is a line read by `semver sort`, kept so it can be printed as it was given, e.g. as a `v1.2` tag.
+type semverLine struct {
+	text    string
+	version *template.Semver
+}
+
+// sortSemvers reads versions, one per line, and sorts them lowest first, or highest first if reverse is set.
+// Lines with equal versions, such as `v1.2` and `1.2.0`, keep their order. Blank lines are ignored, as are lines
+// that aren't versions if skipInvalid is set.
+func sortSemvers(input io.Reader, strict, skipInvalid, reverse bool) ([]semverLine, error) {
+	var lines []semverLine
+	scanner := bufio.NewScanner(input)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		version, err := parseSemver(scanner.Text(), strict)
+		if err != nil {
+			if skipInvalid {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		lines = append(lines, semverLine{text: scanner.Text(), version: version})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		if reverse {
+			return lines[j].version.LessThan(*lines[i].version)
+		}
+		return lines[i].version.LessThan(*lines[j].version)
+	})
+	return lines, nil
+}
+
+func parseSemver(version string, strict bool) (*template.Semver, error) {
+	if strict {
+		return template.NewSemver(version, template.SemverStrict)
+	}
+	return template.NewSemver(version, template.SemverLenient)
+}
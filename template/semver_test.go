@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/blendlabs/template"
+)
+
+func runSemverTest(stdin string, args ...string) (int, string, string) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := semverMain(args, strings.NewReader(stdin), stdout, stderr)
+	return exitCode, stdout.String(), stderr.String()
+}
+
+func TestBumpSemver(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range []struct {
+		part, preID, metadata, version, expected string
+	}{
+		{"major", "", "", "1.4.2", "2.0.0"},
+		{"minor", "", "", "1.4.2-rc.1", "1.5.0"},
+		{"patch", "", "sha.1", "1.4.2", "1.4.3+sha.1"},
+		{"prerelease", "rc", "", "1.5.0-rc.1", "1.5.0-rc.2"},
+		{"prerelease", "rc", "", "1.5.0", "1.5.1-rc.1"},
+		{"release", "", "", "1.5.0-rc.2+sha.1", "1.5.0"},
+	} {
+		version, err := template.NewSemver(test.version)
+		assert.Nil(err)
+		assert.Nil(bumpSemver(version, test.part, test.preID, test.metadata))
+		assert.Equal(test.expected, version.String(), test.part+" "+test.version)
+	}
+
+	version, err := template.NewSemver("1.4.2")
+	assert.Nil(err)
+	assert.NotNil(bumpSemver(version, "build", "", ""))
+}
+
+func TestSortSemvers(t *testing.T) {
+	assert := assert.New(t)
+
+	input := "v1.10.0\n1.2.0\n\n1.2.0-rc.1\nv1.2\n1.2.0-rc.10\n1.2.0-rc.2\nlatest\n"
+
+	_, err := sortSemvers(strings.NewReader(input), false, false, false)
+	assert.NotNil(err)
+	assert.Contains("line 8", err.Error())
+
+	lines, err := sortSemvers(strings.NewReader(input), false, true, false)
+	assert.Nil(err)
+	assert.Equal([]string{"1.2.0-rc.1", "1.2.0-rc.2", "1.2.0-rc.10", "1.2.0", "v1.2", "v1.10.0"}, semverLineTexts(lines))
+
+	lines, err = sortSemvers(strings.NewReader(input), false, true, true)
+	assert.Nil(err)
+	assert.Equal([]string{"v1.10.0", "1.2.0", "v1.2", "1.2.0-rc.10", "1.2.0-rc.2", "1.2.0-rc.1"}, semverLineTexts(lines))
+
+	lines, err = sortSemvers(strings.NewReader("v1.2.0\n1.0.0\n"), true, true, false)
+	assert.Nil(err)
+	assert.Len(lines, 1)
+
+	_, err = sortSemvers(strings.NewReader("1.0.0\n"+strings.Repeat("1", 70*1024)+"\n"), false, true, false)
+	assert.NotNil(err)
+}
+
+func semverLineTexts(lines []semverLine) []string {
+	var texts []string
+	for _, line := range lines {
+		texts = append(texts, line.text)
+	}
+	return texts
+}
+
+func TestSemverExitCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	exitCode, stdout, _ := runSemverTest("", "bump", "minor", "1.4.2")
+	assert.Equal(semverExitTrue, exitCode)
+	assert.Equal("1.5.0\n", stdout)
+
+	exitCode, stdout, _ = runSemverTest("", "compare", "v1.2", "1.10.0")
+	assert.Equal(semverExitTrue, exitCode)
+	assert.Equal("-1\n", stdout)
+
+	exitCode, stdout, _ = runSemverTest("v1.10.0\nrelease-2019\nv1.2\n", "sort", "-r", "-skip-invalid")
+	assert.Equal(semverExitTrue, exitCode)
+	assert.Equal("v1.10.0\nv1.2\n", stdout)
+
+	exitCode, _, _ = runSemverTest("", "satisfies", "^1.2", "1.3.0", "1.9.9")
+	assert.Equal(semverExitTrue, exitCode)
+
+	exitCode, _, stderr := runSemverTest("", "satisfies", "^1.2", "1.3.0", "2.3.0")
+	assert.Equal(semverExitFalse, exitCode)
+	assert.Contains("2.3.0 does not satisfy `^1.2`", stderr)
+
+	for _, args := range [][]string{
+		{},
+		{"nope"},
+		{"bump", "minor"},
+		{"bump", "build", "1.4.2"},
+		{"bump", "-strict", "patch", "v1.2.3"},
+		{"bump", "-nope", "patch", "1.2.3"},
+		{"compare", "1.2.3", "foo"},
+		{"satisfies", ">=foo", "1.2.3"},
+		{"satisfies", "^1.2", "1.x"},
+	} {
+		exitCode, _, _ = runSemverTest("", args...)
+		assert.Equal(semverExitError, exitCode, strings.Join(args, " "))
+	}
+
+	exitCode, _, _ = runSemverTest("1.0.0\nlatest\n", "sort")
+	assert.Equal(semverExitError, exitCode)
+}
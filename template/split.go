@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/blendlabs/template"
+)
+
+// writeDocuments splits rendered yaml into its documents and writes each to its own file in dir.
+// Every name is checked before anything is written, so a bad pattern doesn't leave a partial split behind.
+func writeDocuments(dir, pattern string, output []byte) error {
+	documents := template.SplitDocuments(output)
+	names := make([]string, len(documents))
+	indexes := map[string]int{}
+	for index, document := range documents {
+		name, err := template.DocumentName(pattern, document, index+1)
+		if err != nil {
+			return err
+		}
+		if previous, isNamed := indexes[name]; isNamed {
+			return fmt.Errorf("documents %d and %d are both named `%s`; use a more specific -split-pattern", previous, index+1, name)
+		}
+		indexes[name] = index + 1
+		names[index] = name
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for index, document := range documents {
+		if err := ioutil.WriteFile(filepath.Join(dir, names[index]), []byte(document), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
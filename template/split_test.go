@@ -0,0 +1,30 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestWriteDocuments(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "template-split")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "manifests")
+	output := []byte("kind: Deployment\nmetadata:\n  name: web\n---\nkind: Service\nmetadata:\n  name: web\n")
+	assert.Nil(writeDocuments(dir, "{{kind}}-{{metadata.name}}.yml", output))
+	assert.Equal("kind: Service\nmetadata:\n  name: web\n", readTestFile(t, filepath.Join(dir, "Service-web.yml")))
+
+	for _, pattern := range []string{"{{metadata.name}}.yml", "{{kind}}-{{metadata.name}}/.."} {
+		dir = filepath.Join(root, "invalid")
+		assert.NotNil(writeDocuments(dir, pattern, output), pattern)
+		_, err = os.Stat(dir)
+		assert.True(os.IsNotExist(err), pattern)
+	}
+}
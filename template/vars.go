@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/blendlabs/template"
+)
+
+// runVars implements the `vars` subcommand, which lists every variable, env variable and file a template references.
+// If vars files are given, it instead lists the required variables they don't cover and exits non-zero if there are any.
+func runVars(args []string) {
+	flags := flag.NewFlagSet("vars", flag.ExitOnError)
+
+	var templateFile string
+	flags.StringVar(&templateFile, "f", "", "Template file to inspect; if \"-\", will read from os.Stdin")
+
+	var includes Includes
+	flags.Var(&includes, "i", "Files to include as sub templates; can be a glob")
+
+	var includePaths IncludePaths
+	flags.Var(&includePaths, "I", "Directories searched for -i includes; can be repeated")
+
+	var varsFiles VarsFiles
+	flags.Var(&varsFiles, "vars", "Vars files to check the template against; can be repeated")
+
+	var sprig bool
+	flags.BoolVar(&sprig, "sprig", false, "Adds Sprig (Helm) compatible functions, so templates using them parse")
+
+	var commandFuncs CommandFuncs
+	flags.Var(&commandFuncs, "func", "Template functions implemented by commands, so templates using them parse; the commands aren't run")
+
+	var keyFile string
+	flags.StringVar(&keyFile, "key-file", "", "File holding the encryption key for encrypted vars files; defaults to the TEMPLATE_KEY env variable")
+
+	var asJSON bool
+	flags.BoolVar(&asJSON, "json", false, "Prints the references as json")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s vars -f <template> [-i <include>] [-vars <vars file>] [-json]\n\n", os.Args[0])
+		flags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample Usage:\n")
+		fmt.Fprintf(os.Stderr, "List what a template references: template vars -f deployment.yml\n")
+		fmt.Fprintf(os.Stderr, "Check a vars file covers a template: template vars -f deployment.yml -vars prod.yml\n")
+	}
+	flags.Parse(args)
+
+	if len(templateFile) == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	temp, err := loadTemplate(templateFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	includeFiles, err := loadIncludes(includes, includePaths)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, include := range includeFiles {
+		temp = temp.WithNamedInclude(include.name, include.body)
+	}
+	if sprig {
+		temp = temp.WithSprig()
+	}
+	temp = temp.WithFuncs(commandFuncs.Funcs())
+
+	references, err := temp.References()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(varsFiles) > 0 {
+		vars, err := loadVarsFiles(varsFiles, template.ListMergeReplace, keyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		temp = temp.WithVars(vars)
+
+		var missing []template.Reference
+		for _, reference := range references {
+			if reference.Kind == template.ReferenceVar && reference.IsRequired() && !temp.HasVar(reference.Key) {
+				missing = append(missing, reference)
+			}
+		}
+		printReferences(missing, asJSON)
+		if len(missing) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	printReferences(references, asJSON)
+}
+
+func printReferences(references []template.Reference, asJSON bool) {
+	if asJSON {
+		if references == nil {
+			references = []template.Reference{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(references); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "KIND\tKEY\tDEFAULT\tLOCATION")
+	for _, reference := range references {
+		defaultValue := "-"
+		if reference.HasDefault {
+			defaultValue = reference.Default
+		}
+		key := reference.Key
+		if reference.Method != "Var" && reference.Method != "Env" && reference.Method != "File" {
+			key = fmt.Sprintf("%s (%s)", key, reference.Method)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", reference.Kind, key, defaultValue, reference.Location())
+	}
+	writer.Flush()
+}
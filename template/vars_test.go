@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestVariablesCommandlineOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "template-vars")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	writeTestFile(t, filepath.Join(dir, "name.txt"), "from-file")
+
+	var variables Variables
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.Var(&variables, "var", "")
+	flags.Var(FileVariables{&variables}, "var-file", "")
+	flags.Var(Literals{&variables}, "var-json", "")
+	flags.Var(Integers{&variables}, "var-int", "")
+
+	assert.Nil(flags.Parse([]string{
+		"--var-int=replicas=3",
+		"--var=replicas=5",
+		"--var-json=db={\"host\": \"localhost\"}",
+		"--var=db.host=prod.db",
+		"--var-file=name=" + filepath.Join(dir, "name.txt"),
+		"--var-int=replicas=7",
+	}))
+	assert.Equal([]string{filepath.Join(dir, "name.txt")}, variables.Paths())
+
+	values, err := variables.Values()
+	assert.Nil(err)
+	var keys []string
+	for _, value := range values {
+		keys = append(keys, value.key)
+	}
+	assert.Equal([]string{"replicas", "replicas", "db", "db.host", "name", "replicas"}, keys)
+	assert.Equal("from-file", values[4].value)
+	assert.Equal(int64(7), values[5].value)
+
+	assert.NotNil(flags.Parse([]string{"--var-int=replicas=three"}))
+	assert.NotNil(flags.Parse([]string{"--var-json=nokey"}))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileStamp identifies the version of a file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// watch calls render, then polls the input files it returns and calls it again whenever one changes.
+// Outputs are ignored when walking input directories so rendering doesn't trigger itself.
+// Errors are logged rather than exiting so the template can be fixed while watching.
+func watch(interval time.Duration, outputs []string, render func() ([]string, error)) {
+	for {
+		inputs, err := render()
+		if err != nil {
+			log.Println(err)
+		} else {
+			log.Println("rendered; watching for changes")
+		}
+
+		snapshot := snapshotFiles(inputs, outputs)
+		for {
+			time.Sleep(interval)
+			if current := snapshotFiles(inputs, outputs); !sameSnapshot(snapshot, current) {
+				break
+			}
+		}
+	}
+}
+
+// snapshotFiles stamps each path, walking directories so files added, changed or removed within them are noticed.
+func snapshotFiles(paths, ignore []string) map[string]fileStamp {
+	ignored := map[string]bool{}
+	for _, path := range ignore {
+		if absPath, err := filepath.Abs(path); err == nil {
+			ignored[absPath] = true
+		}
+	}
+
+	snapshot := map[string]fileStamp{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			snapshot[path] = fileStamp{}
+			continue
+		}
+		if !info.IsDir() {
+			snapshot[path] = fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+			continue
+		}
+		filepath.Walk(path, func(child string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if absChild, err := filepath.Abs(child); err == nil && ignored[absChild] {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir() {
+				snapshot[child] = fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+			}
+			return nil
+		})
+	}
+	return snapshot
+}
+
+func sameSnapshot(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stamp := range a {
+		other, hasPath := b[path]
+		if !hasPath || other.exists != stamp.exists || other.size != stamp.size || !other.modTime.Equal(stamp.modTime) {
+			return false
+		}
+	}
+	return true
+}
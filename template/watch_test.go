@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestSnapshotFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	root, err := ioutil.TempDir("", "template-watch")
+	assert.Nil(err)
+	defer os.RemoveAll(root)
+
+	src := filepath.Join(root, "src")
+	out := filepath.Join(src, "out")
+	vars := filepath.Join(root, "vars.yml")
+	writeTestFile(t, filepath.Join(src, "deployment.yml"), "name: test")
+	writeTestFile(t, filepath.Join(src, "nested", "service.yml"), "name: test")
+	writeTestFile(t, filepath.Join(out, "deployment.yml"), "name: rendered")
+	writeTestFile(t, vars, "name: test")
+
+	inputs := []string{src, vars, filepath.Join(root, "missing.yml")}
+	outputs := []string{out}
+	snapshot := snapshotFiles(inputs, outputs)
+	assert.Len(snapshot, 4)
+	_, hasOutput := snapshot[filepath.Join(out, "deployment.yml")]
+	assert.False(hasOutput)
+	assert.True(sameSnapshot(snapshot, snapshotFiles(inputs, outputs)))
+
+	// changes to ignored outputs aren't changes to the inputs.
+	writeTestFile(t, filepath.Join(out, "deployment.yml"), "name: rendered again")
+	writeTestFile(t, filepath.Join(out, "service.yml"), "name: rendered")
+	assert.True(sameSnapshot(snapshot, snapshotFiles(inputs, outputs)))
+
+	later := time.Now().Add(time.Hour)
+	assert.Nil(os.Chtimes(vars, later, later))
+	current := snapshotFiles(inputs, outputs)
+	assert.False(sameSnapshot(snapshot, current), "mtime")
+	snapshot = current
+
+	modTime := snapshot[filepath.Join(src, "deployment.yml")].modTime
+	assert.Nil(ioutil.WriteFile(filepath.Join(src, "deployment.yml"), []byte("name: changed"), 0644))
+	assert.Nil(os.Chtimes(filepath.Join(src, "deployment.yml"), modTime, modTime))
+	current = snapshotFiles(inputs, outputs)
+	assert.False(sameSnapshot(snapshot, current), "size")
+	snapshot = current
+
+	writeTestFile(t, filepath.Join(src, "nested", "ingress.yml"), "name: test")
+	current = snapshotFiles(inputs, outputs)
+	assert.False(sameSnapshot(snapshot, current), "added")
+	snapshot = current
+
+	assert.Nil(os.Remove(filepath.Join(src, "nested", "service.yml")))
+	current = snapshotFiles(inputs, outputs)
+	assert.False(sameSnapshot(snapshot, current), "deleted")
+	snapshot = current
+
+	writeTestFile(t, filepath.Join(root, "missing.yml"), "name: test")
+	current = snapshotFiles(inputs, outputs)
+	assert.False(sameSnapshot(snapshot, current), "created")
+}
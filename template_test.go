@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"fmt"
+	"io/ioutil"
 	"os"
 
 	"strings"
@@ -547,6 +548,45 @@ func TestTemplateViewFuncSemverPreRelease(t *testing.T) {
 	assert.Equal("beta1", buffer.String())
 }
 
+func TestTemplateViewFuncSemverCompareAndBump(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{{ semver_compare (.Var "foo") "1.3.0" }} {{ .Var "foo" | semver_bump "minor" }} {{ .Var "foo" | semver | semver_bump "major" }} {{ .Var "foo" }}`
+	temp := New().WithBody(test).WithVar("foo", "1.2.3-beta1")
+	buffer := bytes.NewBuffer(nil)
+	err := temp.Process(buffer)
+	assert.Nil(err)
+	assert.Equal("-1 1.3.0 2.0.0 1.2.3-beta1", buffer.String())
+
+	err = New().WithBody(`{{ "1.2.3" | semver_bump "build" }}`).Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+}
+
+func TestTemplateViewFuncSemverSortAndLatest(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{{ .Var "foo" | semver_sort }} {{ .Var "foo" | semver_latest }}`
+	temp := New().WithBody(test).WithVar("foo", []interface{}{"1.10.0", "1.2.0", "1.2.0-rc.1", "0.9.1"})
+	buffer := bytes.NewBuffer(nil)
+	err := temp.Process(buffer)
+	assert.Nil(err)
+	assert.Equal("[0.9.1 1.2.0-rc.1 1.2.0 1.10.0] 1.10.0", buffer.String())
+}
+
+func TestTemplateViewFuncSemverSatisfies(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{{ if .Var "foo" | semver_satisfies ">=1.2.0 <2.0.0" }}yes{{ else }}no{{ end }}`
+	temp := New().WithBody(test).WithVar("foo", "1.4.2")
+	buffer := bytes.NewBuffer(nil)
+	err := temp.Process(buffer)
+	assert.Nil(err)
+	assert.Equal("yes", buffer.String())
+
+	err = New().WithBody(`{{ "1.2.3" | semver_satisfies ">=foo" }}`).Process(bytes.NewBuffer(nil))
+	assert.NotNil(err)
+}
+
 type label struct {
 	Name string `yaml:"name"`
 	Vaue string `yaml:"value"`
@@ -591,3 +631,223 @@ func TestTemplateWithVars(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal("buz", val)
 }
+
+func TestTemplateVarPath(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{{ .Var "db.host" }}:{{ .Var "db.ports[1]" }}{{ if .HasVar "db.user" }}{{ .Var "db.user" }}{{ end }}`
+	temp := New().WithBody(test).WithVar("db", map[interface{}]interface{}{
+		"host":  "localhost",
+		"ports": []interface{}{5432, 5433},
+	})
+
+	buffer := bytes.NewBuffer(nil)
+	err := temp.Process(buffer)
+	assert.Nil(err)
+	assert.Equal("localhost:5433", buffer.String())
+}
+
+func TestTemplateVarPathPrefersExactKey(t *testing.T) {
+	assert := assert.New(t)
+
+	temp := New().
+		WithVar("db", map[string]interface{}{"host": "nested"}).
+		WithVar("db.host", "flat")
+
+	value, err := temp.Var("db.host")
+	assert.Nil(err)
+	assert.Equal("flat", value)
+}
+
+func TestTemplateSetVarPath(t *testing.T) {
+	assert := assert.New(t)
+
+	temp := New().WithVar("db", map[string]interface{}{"host": "localhost", "port": 5432})
+	assert.Nil(temp.SetVarPath("db.host", "prod.db"))
+
+	value, err := temp.Var("db.host")
+	assert.Nil(err)
+	assert.Equal("prod.db", value)
+	value, err = temp.Var("db.port")
+	assert.Nil(err)
+	assert.Equal(5432, value)
+
+	assert.NotNil(temp.SetVarPath("db..host", "bad"))
+}
+
+func TestTemplateStrictCollectsMissing(t *testing.T) {
+	assert := assert.New(t)
+
+	varName := UUIDv4().String()
+	test := fmt.Sprintf(`{{ .Var "foo" }}
+{{ .Var "bar" "default" }}
+{{- if .HasVar "baz" }}{{ end }}
+  {{ .Var "baz" }} {{ .Env "%s" }}
+{{ .Var "foo" }}`, varName)
+	temp := New().WithBody(test).WithStrict(true)
+
+	buffer := bytes.NewBuffer(nil)
+	err := temp.Process(buffer)
+	assert.NotNil(err)
+
+	missingErr, isMissingErr := err.(*MissingVarsError)
+	assert.True(isMissingErr)
+	assert.Nil(missingErr.Err)
+	assert.Len(missingErr.Missing, 4)
+
+	assert.Equal("foo", missingErr.Missing[0].Key)
+	assert.Equal("template:1:3", missingErr.Missing[0].Location())
+	assert.Equal("foo", missingErr.Missing[1].Key)
+	assert.Equal(5, missingErr.Missing[1].Line)
+	assert.Equal("baz", missingErr.Missing[2].Key)
+	assert.Equal(4, missingErr.Missing[2].Line)
+	assert.Equal(ReferenceEnv, missingErr.Missing[3].Kind)
+	assert.Equal(varName, missingErr.Missing[3].Key)
+	assert.True(strings.Contains(err.Error(), "template:4:5: var `baz` is unset"))
+
+	assert.Equal("\ndefault\n   \n", buffer.String())
+}
+
+func TestTemplateStrictSucceeds(t *testing.T) {
+	assert := assert.New(t)
+
+	temp := New().WithBody(`{{ .Var "foo" }}`).WithVar("foo", "bar").WithStrict(true)
+
+	buffer := bytes.NewBuffer(nil)
+	err := temp.Process(buffer)
+	assert.Nil(err)
+	assert.Equal("bar", buffer.String())
+}
+
+func TestTemplateStrictStoppedEarly(t *testing.T) {
+	assert := assert.New(t)
+
+	temp := New().WithBody(`{{ .Var "foo" | int }}{{ .Var "bar" }}`).WithStrict(true)
+
+	buffer := bytes.NewBuffer(nil)
+	err := temp.Process(buffer)
+	missingErr, isMissingErr := err.(*MissingVarsError)
+	assert.True(isMissingErr)
+	assert.NotNil(missingErr.Err)
+	assert.Len(missingErr.Missing, 1)
+}
+
+func TestTemplateFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{{ .File "testdata/inline_file" }}{{ if .HasFile "testdata/missing_file" }}yep{{ end }}`
+	temp := New().WithBody(test)
+
+	buffer := bytes.NewBuffer(nil)
+	err := temp.Process(buffer)
+	assert.Nil(err)
+	assert.Equal([]string{"testdata/inline_file", "testdata/missing_file"}, temp.Files())
+
+	err = temp.WithBody(`nothing`).Process(buffer)
+	assert.Nil(err)
+	assert.Empty(temp.Files())
+}
+
+func TestTemplateNamedInclude(t *testing.T) {
+	assert := assert.New(t)
+
+	main := `{{ template "partials/labels.tpl" . }}/{{ template "test" . }}`
+	labels := `{{ .Var "foo" }}`
+	test := `{{ define "test" }}{{ .Var "foo" | upper }}{{end}}`
+
+	buffer := bytes.NewBuffer(nil)
+	err := New().
+		WithBody(main).
+		WithNamedInclude("partials/labels.tpl", labels).
+		WithNamedInclude("partials/test.tpl", test).
+		WithVar("foo", "bar").
+		Process(buffer)
+	assert.Nil(err)
+	assert.Equal("bar/BAR", buffer.String())
+}
+
+func TestTemplateNamedIncludeReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	references, err := New().
+		WithName("main").
+		WithBody(`{{ template "labels.tpl" . }}`).
+		WithNamedInclude("labels.tpl", "\n{{ .Var \"foo\" }}").
+		References()
+	assert.Nil(err)
+	assert.Len(references, 1)
+	assert.Equal("labels.tpl:2:3", references[0].Location())
+}
+
+func TestTemplateViewFuncInclude(t *testing.T) {
+	assert := assert.New(t)
+
+	annotations, err := ioutil.ReadFile("testdata/annotations.yml")
+	assert.Nil(err)
+	temp, err := NewFromFile("testdata/meta.yml")
+	assert.Nil(err)
+
+	temp = temp.
+		WithInclude(string(annotations)).
+		WithVar("name", "test-service").
+		WithVar("test-var", "foo").
+		WithVar("another", "bar")
+
+	buffer := bytes.NewBuffer(nil)
+	err = temp.Process(buffer)
+	assert.Nil(err)
+	assert.True(strings.Contains(buffer.String(), "  annotations:\n    test-var: foo\n    another: bar\nspec:"))
+}
+
+func TestTemplateViewFuncIncludeContext(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{{ define "greeting" }}hello {{ . }}{{ end }}{{ include "greeting" "world" | upper }}`
+
+	buffer := bytes.NewBuffer(nil)
+	err := New().WithBody(test).Process(buffer)
+	assert.Nil(err)
+	assert.Equal("HELLO WORLD", buffer.String())
+}
+
+func TestTemplateViewFuncIncludeErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	err := New().WithBody(`{{ include "missing" . }}`).Process(buffer)
+	assert.NotNil(err)
+
+	err = New().WithBody(`{{ define "loop" }}{{ include "loop" . }}{{ end }}{{ include "loop" . }}`).Process(buffer)
+	assert.NotNil(err)
+	assert.True(strings.Contains(err.Error(), "exceeded maximum include depth"))
+}
+
+func TestTemplateWithFuncs(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{{ .Var "foo" | shout }} {{ upper "bar" }}`
+	buffer := bytes.NewBuffer(nil)
+	err := New().
+		WithBody(test).
+		WithVar("foo", "bar").
+		WithFuncs(FuncMap{
+			"shout": func(v string) string { return strings.ToUpper(v) + "!" },
+			"upper": func(v string) string { return "overridden" },
+		}).
+		Process(buffer)
+	assert.Nil(err)
+	assert.Equal("BAR! overridden", buffer.String())
+
+	for _, funcs := range []FuncMap{
+		{"bad": "not a function"},
+		{"bad-name": func() string { return "" }},
+		{"bad": func() (string, string) { return "", "" }},
+	} {
+		var recovered interface{}
+		func() {
+			defer func() { recovered = recover() }()
+			New().WithFuncs(funcs)
+		}()
+		assert.NotNil(recovered)
+	}
+}
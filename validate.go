@@ -0,0 +1,287 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Validator checks rendered output, e.g. that it parses as yaml.
+// Validators should return an `*OutputError` for invalid output so the error can be mapped back to the template.
+type Validator func(output []byte) error
+
+// ParseValidator returns a built-in validator by name, either `yaml` or `json`.
+func ParseValidator(name string) (Validator, error) {
+	switch strings.ToLower(name) {
+	case "yaml", "yml":
+		return ValidateYAML, nil
+	case "json":
+		return ValidateJSON, nil
+	default:
+		return nil, fmt.Errorf("invalid validator `%s`; must be `yaml` or `json`", name)
+	}
+}
+
+// OutputError is an error in rendered output.
+type OutputError struct {
+	// Line is the 1-based line of the output the error is on, or 0 if it is not known.
+	Line int
+	Err  error
+}
+
+// Error implements error.
+func (e *OutputError) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// ValidationError is returned by `Process` when the rendered output fails validation.
+type ValidationError struct {
+	Err error
+	// OutputLine is the 1-based line of the output the error is on, or 0 if it is not known.
+	OutputLine int
+	// Template, Line and Column locate the template node that rendered the offending output line, if known.
+	Template string
+	Line     int
+	Column   int
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("rendered output is invalid: %v", e.Err)
+	}
+	return fmt.Sprintf("%s:%d: rendered output is invalid: %v", e.Template, e.Line, e.Err)
+}
+
+// yamlErrorLine matches the line number yaml includes in its error messages.
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// ValidateYAML validates that output is a stream of one or more yaml documents separated by `---`.
+func ValidateYAML(output []byte) error {
+	lines := strings.SplitAfter(string(output), "\n")
+
+	var document bytes.Buffer
+	documentStart := 1
+	validate := func() error {
+		var value interface{}
+		err := yaml.Unmarshal(document.Bytes(), &value)
+		if err == nil {
+			return nil
+		}
+		// yaml reports 0-based lines relative to the document, and omits line 0 entirely.
+		message := strings.TrimPrefix(err.Error(), "yaml: ")
+		outputLine := documentStart
+		if match := yamlErrorLine.FindStringSubmatch(message); match != nil && strings.HasPrefix(message, match[0]+": ") {
+			line, _ := strconv.Atoi(match[1])
+			outputLine = documentStart + line
+			message = strings.TrimPrefix(message, match[0]+": ")
+		}
+		return &OutputError{Line: outputLine, Err: fmt.Errorf("invalid yaml: %s", message)}
+	}
+
+	for index, line := range lines {
+		if isYAMLDocumentSeparator(line) {
+			if err := validate(); err != nil {
+				return err
+			}
+			document.Reset()
+			documentStart = index + 2
+			continue
+		}
+		document.WriteString(line)
+	}
+	return validate()
+}
+
+func isYAMLDocumentSeparator(line string) bool {
+	line = strings.TrimRight(line, "\r\n")
+	return line == "---" || strings.HasPrefix(line, "--- ") || line == "..."
+}
+
+// ValidateJSON validates that output is a stream of one or more json values.
+func ValidateJSON(output []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			offset := int64(len(output))
+			if syntaxErr, isSyntaxErr := err.(*json.SyntaxError); isSyntaxErr {
+				offset = syntaxErr.Offset
+			}
+			return &OutputError{Line: lineOfOffset(output, int(offset)), Err: fmt.Errorf("invalid json: %v", err)}
+		}
+	}
+}
+
+// lineOfOffset returns the 1-based line of a byte offset.
+func lineOfOffset(output []byte, offset int) int {
+	if offset > len(output) {
+		offset = len(output)
+	}
+	if offset > 0 && output[offset-1] == '\n' {
+		offset--
+	}
+	return bytes.Count(output[:offset], []byte("\n")) + 1
+}
+
+// outputMarkFunc is the function injected into instrumented templates to record which node renders which output.
+const outputMarkFunc = "__template_output_mark"
+
+// outputTracker records the output offset each template node starts rendering at.
+type outputTracker struct {
+	template *Template
+	output   *bytes.Buffer
+	nodes    []trackedNode
+	marks    []outputMark
+}
+
+type trackedNode struct {
+	template string
+	line     int
+	column   int
+	isText   bool
+}
+
+type outputMark struct {
+	offset int
+	node   int
+}
+
+// trackOutput instruments a parsed template set so rendering into output records where each node's output starts.
+func (t *Template) trackOutput(final *texttemplate.Template, output *bytes.Buffer) (*outputTracker, error) {
+	tracker := &outputTracker{template: t, output: output}
+	final.Funcs(texttemplate.FuncMap{outputMarkFunc: tracker.mark})
+
+	seen := map[*parse.Tree]bool{}
+	for _, temp := range final.Templates() {
+		if temp.Tree == nil || temp.Tree.Root == nil || seen[temp.Tree] {
+			continue
+		}
+		seen[temp.Tree] = true
+		if err := tracker.instrument(temp.Tree, temp.Tree.Root); err != nil {
+			return nil, err
+		}
+	}
+	return tracker, nil
+}
+
+// instrument inserts a mark before every node in a list, recursing into control structures.
+func (o *outputTracker) instrument(tree *parse.Tree, list *parse.ListNode) error {
+	if list == nil {
+		return nil
+	}
+
+	nodes := make([]parse.Node, 0, len(list.Nodes)*2)
+	for _, node := range list.Nodes {
+		name, line, column := nodeLocation(tree, node)
+		_, isText := node.(*parse.TextNode)
+		o.nodes = append(o.nodes, trackedNode{template: name, line: line, column: column, isText: isText})
+		marker, err := o.markNode(len(o.nodes) - 1)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, marker, node)
+
+		switch typed := node.(type) {
+		case *parse.IfNode:
+			err = o.instrumentBranch(tree, &typed.BranchNode)
+		case *parse.RangeNode:
+			err = o.instrumentBranch(tree, &typed.BranchNode)
+		case *parse.WithNode:
+			err = o.instrumentBranch(tree, &typed.BranchNode)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	list.Nodes = nodes
+	return nil
+}
+
+func (o *outputTracker) instrumentBranch(tree *parse.Tree, branch *parse.BranchNode) error {
+	if err := o.instrument(tree, branch.List); err != nil {
+		return err
+	}
+	return o.instrument(tree, branch.ElseList)
+}
+
+// markNode builds the action `{{ __template_output_mark "<index>" }}`, which renders nothing.
+// The action is parsed rather than constructed so it prints correctly in error messages.
+func (o *outputTracker) markNode(index int) (parse.Node, error) {
+	marker, err := texttemplate.New(outputMarkFunc).
+		Funcs(texttemplate.FuncMap{outputMarkFunc: o.mark}).
+		Parse(fmt.Sprintf("{{ %s %q }}", outputMarkFunc, strconv.Itoa(index)))
+	if err != nil {
+		return nil, err
+	}
+	return marker.Tree.Root.Nodes[0], nil
+}
+
+func (o *outputTracker) mark(index string) string {
+	// output rendered by `include` is captured rather than written, so its nodes can't be tracked.
+	if o.template.includeDepth > 0 {
+		return ""
+	}
+	node, _ := strconv.Atoi(index)
+	o.marks = append(o.marks, outputMark{offset: o.output.Len(), node: node})
+	return ""
+}
+
+// validationError wraps a validator error with the template location that rendered the offending output line.
+func (o *outputTracker) validationError(err error) error {
+	validationErr := &ValidationError{Err: err}
+	outputErr, isOutputErr := err.(*OutputError)
+	if !isOutputErr || outputErr.Line == 0 {
+		return validationErr
+	}
+	validationErr.OutputLine = outputErr.Line
+
+	output := o.output.Bytes()
+	lineStart := 0
+	for line := 1; line < outputErr.Line && lineStart < len(output); line++ {
+		next := bytes.IndexByte(output[lineStart:], '\n')
+		if next < 0 {
+			break
+		}
+		lineStart += next + 1
+	}
+
+	var found *outputMark
+	for index := range o.marks {
+		if o.marks[index].offset > lineStart {
+			break
+		}
+		found = &o.marks[index]
+	}
+	if found == nil {
+		return validationErr
+	}
+
+	node := o.nodes[found.node]
+	validationErr.Template = node.template
+	validationErr.Line = node.line
+	validationErr.Column = node.column
+	if node.isText {
+		// text renders verbatim, so count lines from the start of the text node.
+		if lines := bytes.Count(output[found.offset:lineStart], []byte("\n")); lines > 0 {
+			validationErr.Line += lines
+			validationErr.Column = 0
+		}
+	}
+	return validationErr
+}
@@ -0,0 +1,105 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestValidateYAML(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(ValidateYAML([]byte("foo: bar\n---\nbaz: [1, 2]\n---\n")))
+
+	err := ValidateYAML([]byte("foo: bar\n---\nbaz: buzz\n  bad: indent\n"))
+	assert.NotNil(err)
+	outputErr, isOutputErr := err.(*OutputError)
+	assert.True(isOutputErr)
+	assert.Equal(4, outputErr.Line)
+}
+
+func TestValidateJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(ValidateJSON([]byte(`{"foo": "bar"} {"baz": [1, 2]}`)))
+
+	err := ValidateJSON([]byte("{\n  \"foo\": \"bar\",\n}\n"))
+	assert.NotNil(err)
+	outputErr, isOutputErr := err.(*OutputError)
+	assert.True(isOutputErr)
+	assert.Equal(3, outputErr.Line)
+}
+
+func TestParseValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	validator, err := ParseValidator("yaml")
+	assert.Nil(err)
+	assert.NotNil(validator)
+
+	_, err = ParseValidator("xml")
+	assert.NotNil(err)
+}
+
+func TestTemplateWithValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `kind: Service
+metadata:
+  name: {{ .Var "name" }}
+{{- if .HasVar "labels" }}
+  labels:
+{{ .Var "labels" | indentSpaces 2 }}
+{{- end }}
+spec:
+  type: NodePort
+`
+	buffer := bytes.NewBuffer(nil)
+	err := New().WithName("service.yml").WithBody(test).WithValidator(ValidateYAML).
+		WithVar("name", "test-service").
+		WithVar("labels", "  service: test-service").
+		Process(buffer)
+	assert.Nil(err)
+	assert.NotEmpty(buffer.String())
+
+	buffer = bytes.NewBuffer(nil)
+	err = New().WithName("service.yml").WithBody(test).WithValidator(ValidateYAML).
+		WithVar("name", "test-service").
+		WithVar("labels", "service: test-service\n bad: indent").
+		Process(buffer)
+	assert.NotNil(err)
+	assert.Empty(buffer.String())
+
+	validationErr, isValidationErr := err.(*ValidationError)
+	assert.True(isValidationErr)
+	assert.Equal("service.yml", validationErr.Template)
+	assert.Equal(6, validationErr.Line)
+}
+
+func TestTemplateWithValidatorMapsTextLines(t *testing.T) {
+	assert := assert.New(t)
+
+	test := `{
+  "name": "{{ .Var "name" }}",
+  "replicas": 2,
+}`
+	buffer := bytes.NewBuffer(nil)
+	err := New().WithBody(test).WithValidator(ValidateJSON).WithVar("name", "test-service").Process(buffer)
+	assert.NotNil(err)
+
+	validationErr, isValidationErr := err.(*ValidationError)
+	assert.True(isValidationErr)
+	assert.Equal(4, validationErr.OutputLine)
+	assert.Equal(4, validationErr.Line)
+}
+
+func TestTemplateWithValidatorExecutionError(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	err := New().WithBody(`{{ range .Var "foo" }}{{ . }}{{ end }}`).WithValidator(ValidateYAML).WithVar("foo", true).Process(buffer)
+	assert.NotNil(err)
+	_, isValidationErr := err.(*ValidationError)
+	assert.False(isValidationErr)
+}
@@ -0,0 +1,292 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ListMergeStrategy determines how lists are combined when vars are merged.
+type ListMergeStrategy int
+
+const (
+	// ListMergeReplace replaces lists in the base vars with lists from the overlay.
+	ListMergeReplace ListMergeStrategy = iota
+	// ListMergeAppend appends lists from the overlay onto lists in the base vars.
+	ListMergeAppend
+)
+
+// ParseListMergeStrategy parses a list merge strategy from its name, either `replace` or `append`.
+func ParseListMergeStrategy(name string) (ListMergeStrategy, error) {
+	switch name {
+	case "", "replace":
+		return ListMergeReplace, nil
+	case "append":
+		return ListMergeAppend, nil
+	default:
+		return ListMergeReplace, fmt.Errorf("invalid list merge strategy `%s`; must be `replace` or `append`", name)
+	}
+}
+
+// MergeVars deep merges overlay onto base and returns the result; neither input is modified.
+// Maps are merged recursively, lists are combined according to the strategy, and any other value in
+// the overlay replaces the value in base. Nested maps in the result are normalized to map[string]interface{}.
+func MergeVars(base, overlay Vars, lists ListMergeStrategy) Vars {
+	merged, _ := mergeValues(normalizeValue(base), normalizeValue(overlay), lists).(map[string]interface{})
+	if merged == nil {
+		merged = Vars{}
+	}
+	return merged
+}
+
+func mergeValues(base, overlay interface{}, lists ListMergeStrategy) interface{} {
+	switch typedOverlay := overlay.(type) {
+	case map[string]interface{}:
+		typedBase, isMap := base.(map[string]interface{})
+		if !isMap {
+			return typedOverlay
+		}
+		merged := map[string]interface{}{}
+		for key, value := range typedBase {
+			merged[key] = value
+		}
+		for key, value := range typedOverlay {
+			if existing, hasKey := merged[key]; hasKey {
+				merged[key] = mergeValues(existing, value, lists)
+			} else {
+				merged[key] = value
+			}
+		}
+		return merged
+	case []interface{}:
+		typedBase, isList := base.([]interface{})
+		if !isList || lists != ListMergeAppend {
+			return typedOverlay
+		}
+		merged := make([]interface{}, 0, len(typedBase)+len(typedOverlay))
+		merged = append(merged, typedBase...)
+		return append(merged, typedOverlay...)
+	default:
+		return overlay
+	}
+}
+
+// normalizeValue copies a value, converting the map[interface{}]interface{} maps yaml produces into string keyed maps.
+func normalizeValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(typed))
+		for key, elem := range typed {
+			normalized[key] = normalizeValue(elem)
+		}
+		return normalized
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(typed))
+		for key, elem := range typed {
+			normalized[fmt.Sprintf("%v", key)] = normalizeValue(elem)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(typed))
+		for index, elem := range typed {
+			normalized[index] = normalizeValue(elem)
+		}
+		return normalized
+	default:
+		return value
+	}
+}
+
+// varPathSegment is a single step in a variable path, either a map key or a list index.
+type varPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseVarPath parses a dotted variable path such as `db.hosts[0].name` into its segments.
+func parseVarPath(path string) ([]varPathSegment, error) {
+	var segments []varPathSegment
+	var key []rune
+	expectKey := true
+
+	runes := []rune(path)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '.':
+			if expectKey && len(key) == 0 {
+				return nil, fmt.Errorf("invalid variable path `%s`: empty key at position %d", path, i)
+			}
+			if len(key) > 0 {
+				segments = append(segments, varPathSegment{key: string(key)})
+				key = nil
+			}
+			expectKey = true
+		case '[':
+			if len(key) > 0 {
+				segments = append(segments, varPathSegment{key: string(key)})
+				key = nil
+			} else if len(segments) == 0 || expectKey {
+				return nil, fmt.Errorf("invalid variable path `%s`: index without a key at position %d", path, i)
+			}
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("invalid variable path `%s`: unterminated index at position %d", path, i)
+			}
+			index, err := strconv.Atoi(string(runes[i+1 : end]))
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid variable path `%s`: index must be a non-negative integer at position %d", path, i)
+			}
+			segments = append(segments, varPathSegment{index: index, isIndex: true})
+			i = end
+			expectKey = false
+		case ']':
+			return nil, fmt.Errorf("invalid variable path `%s`: unexpected `]` at position %d", path, i)
+		default:
+			if !expectKey {
+				return nil, fmt.Errorf("invalid variable path `%s`: expected `.` or `[` at position %d", path, i)
+			}
+			key = append(key, runes[i])
+		}
+	}
+	if len(key) > 0 {
+		segments = append(segments, varPathSegment{key: string(key)})
+	} else if expectKey {
+		return nil, fmt.Errorf("invalid variable path `%s`: empty key at position %d", path, len(runes))
+	}
+	return segments, nil
+}
+
+// lookupVarPath resolves a dotted variable path against a set of vars, or any other map or list.
+func lookupVarPath(vars interface{}, path string) (interface{}, bool) {
+	segments, err := parseVarPath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var current interface{} = vars
+	for _, segment := range segments {
+		value := reflect.ValueOf(current)
+		if !value.IsValid() {
+			return nil, false
+		}
+		if value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+
+		if segment.isIndex {
+			if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+				return nil, false
+			}
+			if segment.index >= value.Len() {
+				return nil, false
+			}
+			current = value.Index(segment.index).Interface()
+			continue
+		}
+
+		if value.Kind() != reflect.Map {
+			return nil, false
+		}
+		var elem reflect.Value
+		for _, mapKey := range value.MapKeys() {
+			if fmt.Sprintf("%v", mapKey.Interface()) == segment.key {
+				elem = value.MapIndex(mapKey)
+				break
+			}
+		}
+		if !elem.IsValid() {
+			return nil, false
+		}
+		current = elem.Interface()
+	}
+	return current, true
+}
+
+// setVarPath sets a value at a dotted variable path, creating intermediate maps and lists as needed.
+// Containers along the path are copied rather than modified in place. As with lookups, a flat key that matches
+// the whole path, such as "db.host", is overwritten rather than nested.
+func setVarPath(vars Vars, path string, value interface{}) (Vars, error) {
+	if _, hasKey := vars[path]; hasKey {
+		updated := Vars{}
+		for key, existing := range vars {
+			updated[key] = existing
+		}
+		updated[path] = value
+		return updated, nil
+	}
+
+	segments, err := parseVarPath(path)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := setPathValue(vars, segments, value, "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot set variable `%s`: %v", path, err)
+	}
+	return updated.(map[string]interface{}), nil
+}
+
+func setPathValue(current interface{}, segments []varPathSegment, value interface{}, prefix string) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	segment := segments[0]
+
+	if segment.isIndex {
+		var list []interface{}
+		switch typed := current.(type) {
+		case nil:
+		case []interface{}:
+			list = append(list, typed...)
+		default:
+			return nil, fmt.Errorf("`%s` is not a list", prefix)
+		}
+		elemPath := fmt.Sprintf("%s[%d]", prefix, segment.index)
+		if segment.index > len(list) {
+			return nil, fmt.Errorf("index out of range at `%s`", elemPath)
+		}
+		var existing interface{}
+		if segment.index < len(list) {
+			existing = list[segment.index]
+		}
+		elem, err := setPathValue(existing, segments[1:], value, elemPath)
+		if err != nil {
+			return nil, err
+		}
+		if segment.index == len(list) {
+			return append(list, elem), nil
+		}
+		list[segment.index] = elem
+		return list, nil
+	}
+
+	values := map[string]interface{}{}
+	switch typed := current.(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, elem := range typed {
+			values[key] = elem
+		}
+	case map[interface{}]interface{}:
+		for key, elem := range typed {
+			values[fmt.Sprintf("%v", key)] = elem
+		}
+	default:
+		return nil, fmt.Errorf("`%s` is not a map", prefix)
+	}
+
+	elemPath := segment.key
+	if len(prefix) > 0 {
+		elemPath = prefix + "." + segment.key
+	}
+	elem, err := setPathValue(values[segment.key], segments[1:], value, elemPath)
+	if err != nil {
+		return nil, err
+	}
+	values[segment.key] = elem
+	return values, nil
+}
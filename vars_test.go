@@ -0,0 +1,160 @@
+package template
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMergeVars(t *testing.T) {
+	assert := assert.New(t)
+
+	base := Vars{}
+	assert.Nil(yaml.Unmarshal([]byte(`
+name: test-service
+db:
+  host: localhost
+  port: 5432
+tags: [a, b]
+`), &base))
+
+	overlay := Vars{}
+	assert.Nil(yaml.Unmarshal([]byte(`
+db:
+  host: prod.db
+tags: [c]
+`), &overlay))
+
+	merged := MergeVars(base, overlay, ListMergeReplace)
+	assert.Equal("test-service", merged["name"])
+	db, isMap := merged["db"].(map[string]interface{})
+	assert.True(isMap)
+	assert.Equal("prod.db", db["host"])
+	assert.Equal(5432, db["port"])
+	assert.Equal([]interface{}{"c"}, merged["tags"])
+
+	merged = MergeVars(base, overlay, ListMergeAppend)
+	assert.Equal([]interface{}{"a", "b", "c"}, merged["tags"])
+
+	// the inputs are left untouched.
+	_, isYAMLMap := base["db"].(map[interface{}]interface{})
+	assert.True(isYAMLMap)
+}
+
+func TestMergeVarsReplacesMismatchedTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	merged := MergeVars(Vars{"foo": map[string]interface{}{"bar": "baz"}}, Vars{"foo": "buzz"}, ListMergeReplace)
+	assert.Equal("buzz", merged["foo"])
+}
+
+func TestParseListMergeStrategy(t *testing.T) {
+	assert := assert.New(t)
+
+	strategy, err := ParseListMergeStrategy("append")
+	assert.Nil(err)
+	assert.Equal(ListMergeAppend, strategy)
+
+	strategy, err = ParseListMergeStrategy("")
+	assert.Nil(err)
+	assert.Equal(ListMergeReplace, strategy)
+
+	_, err = ParseListMergeStrategy("zip")
+	assert.NotNil(err)
+}
+
+func TestTemplateWithMergedVars(t *testing.T) {
+	assert := assert.New(t)
+
+	temp := New().
+		WithVar("db", map[string]interface{}{"host": "localhost", "port": 5432}).
+		WithMergedVars(Vars{"db": map[string]interface{}{"host": "prod.db"}}, ListMergeReplace)
+
+	db, err := temp.Var("db")
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"host": "prod.db", "port": 5432}, db)
+}
+
+func TestParseVarPath(t *testing.T) {
+	assert := assert.New(t)
+
+	segments, err := parseVarPath("db.hosts[1].name")
+	assert.Nil(err)
+	assert.Equal([]varPathSegment{
+		{key: "db"},
+		{key: "hosts"},
+		{index: 1, isIndex: true},
+		{key: "name"},
+	}, segments)
+
+	for _, invalid := range []string{"", "db.", ".db", "db..host", "[0]", "db[x]", "db[0", "db]", "db[0]host"} {
+		_, err = parseVarPath(invalid)
+		assert.NotNil(err, invalid)
+	}
+}
+
+func TestLookupVarPath(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := Vars{}
+	assert.Nil(yaml.Unmarshal([]byte(`
+db:
+  host: localhost
+  replicas:
+  - name: replica-a
+  - name: replica-b
+`), &vars))
+
+	value, hasValue := lookupVarPath(vars, "db.host")
+	assert.True(hasValue)
+	assert.Equal("localhost", value)
+
+	value, hasValue = lookupVarPath(vars, "db.replicas[1].name")
+	assert.True(hasValue)
+	assert.Equal("replica-b", value)
+
+	_, hasValue = lookupVarPath(vars, "db.replicas[2].name")
+	assert.False(hasValue)
+	_, hasValue = lookupVarPath(vars, "db.host.name")
+	assert.False(hasValue)
+}
+
+func TestSetVarPath(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := Vars{"db": map[interface{}]interface{}{"host": "localhost", "port": 5432}}
+
+	updated, err := setVarPath(vars, "db.host", "prod.db")
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"host": "prod.db", "port": 5432}, updated["db"])
+	assert.Equal("localhost", vars["db"].(map[interface{}]interface{})["host"])
+
+	updated, err = setVarPath(updated, "db.replicas[0]", "replica-a")
+	assert.Nil(err)
+	updated, err = setVarPath(updated, "db.replicas[1]", "replica-b")
+	assert.Nil(err)
+	assert.Equal([]interface{}{"replica-a", "replica-b"}, updated["db"].(map[string]interface{})["replicas"])
+
+	_, err = setVarPath(updated, "db.replicas[5]", "replica-f")
+	assert.NotNil(err)
+	_, err = setVarPath(updated, "db.host.name", "nope")
+	assert.NotNil(err)
+}
+
+func TestSetVarPathFlatKey(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := Vars{"db.host": "localhost"}
+
+	updated, err := setVarPath(vars, "db.host", "prod.db")
+	assert.Nil(err)
+	assert.Equal(Vars{"db.host": "prod.db"}, updated)
+	assert.Equal("localhost", vars["db.host"])
+
+	temp := New().WithVars(vars)
+	assert.Nil(temp.SetVarPath("db.host", "prod.db"))
+	value, err := temp.Var("db.host")
+	assert.Nil(err)
+	assert.Equal("prod.db", value)
+}